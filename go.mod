@@ -2,4 +2,7 @@ module github.com/glitch-vpn/fracture
 
 go 1.21
 
-require github.com/ulikunitz/xz v0.5.12
+require (
+	github.com/klauspost/compress v1.17.11
+	github.com/ulikunitz/xz v0.5.12
+)
@@ -0,0 +1,162 @@
+package fracture
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadCache(t *testing.T) {
+	dir := t.TempDir()
+	pm := &Manager{cacheDir: filepath.Join(dir, "cache"), progress: io.Discard}
+	const url = "https://example.com/tool.tar.gz"
+
+	source := filepath.Join(dir, "tool.tar.gz")
+	if err := os.WriteFile(source, []byte("release one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := fileSHA256(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.storeInCache(url, hash, source); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(dir, "restored")
+	if pm.restoreFromCache(url, "", target) {
+		t.Error("restored without a checksum")
+	}
+	if pm.restoreFromCache(url, "0000000000000000000000000000000000000000000000000000000000000000", target) {
+		t.Error("restored an entry stored under another checksum")
+	}
+	if pm.restoreFromCache("https://example.com/other.tar.gz", hash, target) {
+		t.Error("restored an entry stored under another URL")
+	}
+	if !pm.restoreFromCache(url, hash, target) {
+		t.Fatal("entry stored under its checksum was not restored")
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "release one" {
+		t.Errorf("restored %q, %v", data, err)
+	}
+
+	// An entry whose content no longer matches is dropped.
+	if err := os.WriteFile(pm.cacheEntryPath(url, hash), []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if pm.restoreFromCache(url, hash, filepath.Join(dir, "corrupted")) {
+		t.Error("restored a corrupted entry")
+	}
+	if _, err := os.Stat(pm.cacheEntryPath(url, hash)); !os.IsNotExist(err) {
+		t.Errorf("corrupted entry was kept: %v", err)
+	}
+}
+func TestUseLockedChecksums(t *testing.T) {
+	resolved := &ResolvedDependency{Version: "v1.0.0", DownloadURL: "https://example.com/a", Asset: &GitHubAsset{Digest: "sha256:published"}}
+	resolved.Parts = []*ResolvedDependency{{Version: "v1.0.0", DownloadURL: "https://example.com/part", Asset: &GitHubAsset{Name: "part"}}}
+
+	resolved.useLockedChecksums(LockDependency{Version: "v0.9.0", URL: "https://example.com/a", SHA256: "old"})
+	if got := resolved.expectedSHA256(); got != "published" {
+		t.Errorf("checksum after a lock entry for another version = %q, want the published digest", got)
+	}
+
+	resolved.useLockedChecksums(LockDependency{Version: "v1.0.0", URL: "https://example.com/a", SHA256: "locked", Assets: []LockAsset{{URL: "https://example.com/part", SHA256: "locked-part"}}})
+	if got := resolved.expectedSHA256(); got != "locked" {
+		t.Errorf("checksum = %q, want the locked one", got)
+	}
+	if got := resolved.Parts[0].expectedSHA256(); got != "locked-part" {
+		t.Errorf("part checksum = %q, want the locked one", got)
+	}
+	if lockDep := resolved.lockDependency(); lockDep.SHA256 != "locked" || lockDep.Assets[0].SHA256 != "locked-part" {
+		t.Errorf("lock entry checksums = %q, %+v", lockDep.SHA256, lockDep.Assets)
+	}
+}
+func TestHashCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tool")
+	if err := os.WriteFile(path, []byte("release one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := fileSHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		entry hashCacheEntry
+		want  string
+	}{
+		{"unchanged file uses the cached hash", hashCacheEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), SHA256: "cached"}, "cached"},
+		{"different size is re-hashed", hashCacheEntry{Size: info.Size() + 1, ModTime: info.ModTime().UnixNano(), SHA256: "cached"}, hash},
+		{"different mtime is re-hashed", hashCacheEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano() - 1, SHA256: "cached"}, hash},
+	}
+	for _, tt := range tests {
+		hashes := hashCache{path: tt.entry}
+		got, err := hashes.fileSHA256(path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+		if hashes[path].SHA256 != tt.want {
+			t.Errorf("%s: cache holds %q, want %q", tt.name, hashes[path].SHA256, tt.want)
+		}
+	}
+
+	pm := &Manager{cacheDir: filepath.Join(dir, "cache")}
+	if err := pm.saveHashCache(hashCache{path: {Size: 1, ModTime: 2, SHA256: hash}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pm.loadHashCache()[path]; got.SHA256 != hash {
+		t.Errorf("reloaded entry = %+v", got)
+	}
+	if err := os.WriteFile(pm.hashCachePath(), []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := pm.loadHashCache(); len(got) != 0 {
+		t.Errorf("damaged cache loaded as %+v", got)
+	}
+}
+func TestDirSHA256(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"a", "sub/b", ".git/index"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hashes := make(hashCache)
+	before, err := dirSHA256(dir, hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, cached := hashes[filepath.Join(dir, "sub", "b")]; !cached {
+		t.Error("file hashes were not cached")
+	}
+	if _, cached := hashes[filepath.Join(dir, ".git", "index")]; cached {
+		t.Error(".git was hashed")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".git", "index"), []byte("touched by git"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := dirSHA256(dir, hashes); err != nil || after != before {
+		t.Errorf("hash after a change in .git = %q, %v, want %q", after, err, before)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "b"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := dirSHA256(dir, hashes); err != nil || after == before {
+		t.Errorf("hash after a change in sub/b = %q, %v, want a new hash", after, err)
+	}
+}
@@ -0,0 +1,134 @@
+package fracture
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStripJSONComments(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "no comments", in: `{"a": 1}`, want: `{"a": 1}`},
+		{name: "line comment", in: "{\"a\": 1 // one\n}", want: "{\"a\": 1       \n}"},
+		{name: "block comment", in: `{/* x */"a": 1}`, want: `{       "a": 1}`},
+		{name: "block comment keeps newlines", in: "{/* a\nb */}", want: "{    \n    }"},
+		{name: "slashes in string", in: `{"url": "https://x//y"}`, want: `{"url": "https://x//y"}`},
+		{name: "comment markers in string", in: `{"a": "/* no */"}`, want: `{"a": "/* no */"}`},
+		{name: "escaped quote in string", in: `{"a": "\" // no"}`, want: `{"a": "\" // no"}`},
+		{name: "unterminated block comment", in: `{} /* open`, want: `{}        `},
+	}
+	for _, tt := range tests {
+		got := string(stripJSONComments([]byte(tt.in)))
+		if got != tt.want {
+			t.Errorf("%s: stripJSONComments(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+		if len(got) != len(tt.in) {
+			t.Errorf("%s: length changed from %d to %d, offsets would shift", tt.name, len(tt.in), len(got))
+		}
+	}
+}
+
+func TestRemoveTrailingCommas(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "object", in: `{"a": 1,}`, want: `{"a": 1 }`},
+		{name: "array", in: `[1, 2,]`, want: `[1, 2 ]`},
+		{name: "before newline", in: "{\"a\": 1,\n}", want: "{\"a\": 1 \n}"},
+		{name: "separating commas stay", in: `{"a": 1, "b": 2}`, want: `{"a": 1, "b": 2}`},
+		{name: "comma in string", in: `{"a": ",}"}`, want: `{"a": ",}"}`},
+		{name: "nested", in: `{"a": [1,], "b": {"c": 2,},}`, want: `{"a": [1 ], "b": {"c": 2 } }`},
+	}
+	for _, tt := range tests {
+		got := string(removeTrailingCommas([]byte(tt.in)))
+		if got != tt.want {
+			t.Errorf("%s: removeTrailingCommas(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStandardizeJSONTrailingCommaBeforeComment(t *testing.T) {
+	in := "{\n  \"a\": 1, // last\n}"
+	var v map[string]int
+	if err := decodeConfigJSON([]byte(in), &v); err != nil {
+		t.Fatalf("decodeConfigJSON(%q) = %v", in, err)
+	}
+	if v["a"] != 1 {
+		t.Errorf("a = %d, want 1", v["a"])
+	}
+}
+
+func TestJSONErrorAt(t *testing.T) {
+	data := []byte("{\n  \"a\": 1,\n\t\"b\" 2\n}")
+	tests := []struct {
+		name   string
+		offset int64
+		want   string
+	}{
+		{name: "first line", offset: 0, want: "line 1, column 1: boom\n    {\n    ^"},
+		{name: "second line", offset: 4, want: "line 2, column 3: boom\n      \"a\": 1,\n      ^"},
+		{name: "tab is shown as a space", offset: 16, want: "line 3, column 5: boom\n     \"b\" 2\n        ^"},
+		{name: "negative offset", offset: -1, want: "boom"},
+		{name: "offset past the end", offset: int64(len(data)), want: "boom"},
+	}
+	for _, tt := range tests {
+		err := jsonErrorAt(data, tt.offset, "boom")
+		if err.Error() != tt.want {
+			t.Errorf("%s: jsonErrorAt(%d) = %q, want %q", tt.name, tt.offset, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestDecodeConfigJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "empty", in: "  ", want: "config is empty"},
+		{name: "syntax", in: "{\n  \"tool\": {\"path\": \"bin\" \"source\": \"x\"}\n}", want: "line 2, column 26: invalid character '\"' after object key:value pair"},
+		{name: "type", in: "{\n  \"tool\": {\"path\": 1}\n}", want: "line 2, column 20: tool.path must be string, got number"},
+		{name: "unknown field", in: "{\n  \"tool\": {\"path\": \"bin\", \"pth\": \"x\"}\n}", want: "line 2, column 27: dependency tool: unknown field \"pth\""},
+		{name: "unknown field in dependencies", in: "{\"dependencies\": {\"tool\": {\"source\": \"x\", \"pth\": \"x\"}}}", want: "line 1, column 43: dependency tool: unknown field \"pth\""},
+		{name: "unknown field in defaults", in: "{\"defaults\": {\"pth\": \"x\"}, \"dependencies\": {}}", want: "line 1, column 15: defaults: unknown field \"pth\""},
+	}
+	for _, tt := range tests {
+		var config ConfigFile
+		err := decodeConfigJSON([]byte(tt.in), &config)
+		if err == nil {
+			t.Errorf("%s: decodeConfigJSON succeeded, want an error", tt.name)
+			continue
+		}
+		got, _, _ := strings.Cut(err.Error(), "\n")
+		if got != tt.want {
+			t.Errorf("%s: decodeConfigJSON error = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+func TestLoadDepsFileLegacyName(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"tool": {"name": "tool", "path": "bin", "source": "https://github.com/o/r"}}`
+	if err := os.WriteFile(filepath.Join(dir, DepsFileName), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	pm := &Manager{workDir: dir, configPath: DepsFileName, overridePath: generateOverrideFileName(DepsFileName), progress: &out}
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		t.Fatalf("loadDepsFile = %v", err)
+	}
+	if deps["tool"].Source != "https://github.com/o/r" {
+		t.Errorf("tool = %+v", deps["tool"])
+	}
+	if !strings.Contains(out.String(), `dependency tool: "name" is ignored`) {
+		t.Errorf("output = %q, want a warning about \"name\"", out.String())
+	}
+}
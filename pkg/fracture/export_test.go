@@ -0,0 +1,66 @@
+package fracture
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExportUsesLock(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tag := "v2.0.0"
+		if strings.HasSuffix(r.URL.Path, "/releases/tags/v1.0.0") {
+			tag = "v1.0.0"
+		}
+		json.NewEncoder(w).Encode(GitHubRelease{TagName: tag, Assets: []GitHubAsset{{
+			Name:               "tool_linux_amd64",
+			BrowserDownloadURL: "https://github.com/o/r/releases/download/" + tag + "/tool_linux_amd64",
+			Digest:             "sha256:published-" + tag,
+		}}})
+	})
+
+	tests := []struct {
+		name        string
+		pinned      bool
+		wantVersion string
+		wantSHA256  string
+	}{
+		{"pinned dependency exports the locked release and checksum", true, "v1.0.0", "locked"},
+		{"unpinned dependency exports what update would fetch", false, "v2.0.0", "published-v2.0.0"},
+	}
+	for _, tt := range tests {
+		deps := DepsFile{"tool": {Source: "https://github.com/o/r", Type: "binary", Path: "bin", AssetSuffix: "linux_amd64", Pinned: tt.pinned}}
+		lock := LockFile{"tool": {
+			Name:    "tool",
+			Source:  "https://github.com/o/r",
+			Type:    "binary",
+			Path:    "bin",
+			Version: "v1.0.0",
+			Hash:    "v1.0.0",
+			URL:     "https://github.com/o/r/releases/download/v1.0.0/tool_linux_amd64",
+			Asset:   "tool_linux_amd64",
+			SHA256:  "locked",
+		}}
+		pm := newTestProject(t, deps, lock, Options{})
+		pm.httpClient = &http.Client{Transport: &recordingTransport{handler: handler}}
+		if err := pm.Export(context.Background()); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		data, err := os.ReadFile(pm.targetPath(generateExportFileName(pm.configPath)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var export ExportFile
+		if err := json.Unmarshal(data, &export); err != nil {
+			t.Fatal(err)
+		}
+		if got := export["tool"]; got.Version != tt.wantVersion || got.SHA256 != tt.wantSHA256 {
+			t.Errorf("%s: exported %s with sha256 %q, want %s with %q", tt.name, got.Version, got.SHA256, tt.wantVersion, tt.wantSHA256)
+		}
+	}
+}
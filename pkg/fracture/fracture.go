@@ -0,0 +1,7163 @@
+// Package fracture installs release assets, sources and repositories
+// described by a fracture.json config. The fracture command is a thin
+// wrapper around Manager.
+package fracture
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Names projects commonly use in asset names for each GOOS/GOARCH value.
+var (
+	osAliases = map[string][]string{
+		"linux":   nil,
+		"darwin":  {"macos", "mac", "osx", "apple"},
+		"windows": {"win", "win32", "win64"},
+		"freebsd": nil,
+		"openbsd": nil,
+		"netbsd":  nil,
+		"android": nil,
+	}
+	archAliases = map[string][]string{
+		"amd64":     {"x86_64", "x64", "x86-64"},
+		"arm64":     {"aarch64"},
+		"386":       {"i386", "i686", "x86"},
+		"arm":       {"armv7", "armv7l", "armv6", "armhf"},
+		"riscv64":   nil,
+		"ppc64le":   nil,
+		"s390x":     nil,
+		"universal": {"all"},
+	}
+)
+
+type Dependency struct {
+	Path               string            `json:"path"`
+	Source             string            `json:"source"`
+	Type               string            `json:"type,omitempty"`
+	AssetSuffix        string            `json:"asset_suffix,omitempty"`
+	AssetOS            string            `json:"asset_os,omitempty"`
+	AssetArch          string            `json:"asset_arch,omitempty"`
+	Private            bool              `json:"private,omitempty"`
+	Extract            bool              `json:"extract,omitempty"`
+	Filename           string            `json:"filename,omitempty"`
+	AssetName          string            `json:"asset_name,omitempty"`
+	AssetExtension     string            `json:"asset_extension,omitempty"`
+	Platforms          []string          `json:"platforms,omitempty"`
+	AssetIndex         *int              `json:"asset_index,omitempty"`
+	Bin                bool              `json:"bin,omitempty"`
+	Mode               string            `json:"mode,omitempty"`
+	Executable         *bool             `json:"executable,omitempty"`
+	SaveChangelog      bool              `json:"save_changelog,omitempty"`
+	KeepVersions       int               `json:"keep_versions,omitempty"`
+	Versions           int               `json:"versions,omitempty"`
+	Mirrors            []string          `json:"mirrors,omitempty"`
+	Ref                string            `json:"ref,omitempty"`
+	TagPrefix          string            `json:"tag_prefix,omitempty"`
+	Prerelease         bool              `json:"prerelease,omitempty"`
+	Resolve            string            `json:"resolve,omitempty"`
+	Interactive        bool              `json:"interactive,omitempty"`
+	Headers            map[string]string `json:"headers,omitempty"`
+	Version            string            `json:"version,omitempty"`
+	Pinned             bool              `json:"pinned,omitempty"`
+	Flatten            bool              `json:"flatten,omitempty"`
+	InstallPackage     bool              `json:"install_package,omitempty"`
+	Requires           []string          `json:"requires,omitempty"`
+	Sparse             []string          `json:"sparse,omitempty"`
+	SatisfiedIfVersion *VersionCheck     `json:"satisfied_if_version,omitempty"`
+	// LegacyName is the "name" older configs repeated in each entry; the
+	// key already names the dependency, so it is ignored.
+	LegacyName string `json:"name,omitempty"`
+	versionOf  string
+}
+type VersionCheck struct {
+	Command    []string `json:"command"`
+	Regex      string   `json:"regex,omitempty"`
+	Constraint string   `json:"constraint"`
+}
+type LockDependency struct {
+	Name           string            `json:"name"`
+	Path           string            `json:"path"`
+	Source         string            `json:"source"`
+	Version        string            `json:"version"`
+	Hash           string            `json:"hash"`
+	Type           string            `json:"type"`
+	Private        bool              `json:"private,omitempty"`
+	Extract        bool              `json:"extract,omitempty"`
+	URL            string            `json:"url,omitempty"`
+	Asset          string            `json:"asset,omitempty"`
+	Mirror         string            `json:"mirror,omitempty"`
+	Ref            string            `json:"ref,omitempty"`
+	Commit         string            `json:"commit,omitempty"`
+	PublishedAt    string            `json:"published_at,omitempty"`
+	SHA256         string            `json:"sha256,omitempty"`
+	Assets         []LockAsset       `json:"assets,omitempty"`
+	PackageVersion string            `json:"package_version,omitempty"`
+	Installed      map[string]string `json:"installed,omitempty"`
+}
+type LockAsset struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+type GitHubAsset struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	Size               int64  `json:"size"`
+	Digest             string `json:"digest"`
+}
+type GitHubRelease struct {
+	TagName     string        `json:"tag_name"`
+	HTMLURL     string        `json:"html_url"`
+	Draft       bool          `json:"draft"`
+	Prerelease  bool          `json:"prerelease"`
+	Body        string        `json:"body"`
+	PublishedAt string        `json:"published_at"`
+	Assets      []GitHubAsset `json:"assets"`
+}
+type GitHubGist struct {
+	Files   map[string]GitHubGistFile `json:"files"`
+	History []struct {
+		Version string `json:"version"`
+	} `json:"history"`
+}
+type GitHubGistFile struct {
+	Filename string `json:"filename"`
+	RawURL   string `json:"raw_url"`
+	Size     int64  `json:"size"`
+}
+type ResolvedDependency struct {
+	Name           string
+	Type           string
+	Dependency     Dependency
+	Owner          string
+	Repo           string
+	Version        string
+	ExpandedPath   string
+	SourceFormat   string
+	DownloadURL    string
+	Asset          *GitHubAsset
+	FileMode       os.FileMode
+	LocalPath      string
+	ReleaseNotes   string
+	Mirror         string
+	Ref            string
+	Commit         string
+	PublishedAt    string
+	Files          []string
+	Parts          []*ResolvedDependency
+	PackageVersion string
+	SHA256         string
+}
+type decisionTrace struct {
+	steps []string
+}
+type jsonMember struct {
+	name       string
+	keyStart   int
+	valueStart int
+	valueEnd   int
+}
+type DepsFile map[string]Dependency
+type ConfigFile struct {
+	Defaults     *ConfigDefaults
+	Lock         string
+	Dependencies DepsFile
+}
+type ConfigDefaults struct {
+	Dir string `json:"dir,omitempty"`
+	Dependency
+}
+type Override struct {
+	Source string `json:"source"`
+}
+type OverridesFile map[string]Override
+type ExportEntry struct {
+	Type    string        `json:"type"`
+	Source  string        `json:"source"`
+	Version string        `json:"version"`
+	Path    string        `json:"path"`
+	URLs    []string      `json:"urls,omitempty"`
+	Asset   string        `json:"asset,omitempty"`
+	Size    int64         `json:"size,omitempty"`
+	SHA256  string        `json:"sha256,omitempty"`
+	Assets  []ExportEntry `json:"assets,omitempty"`
+}
+type ExportFile map[string]ExportEntry
+type ReportEntry struct {
+	Type    string   `json:"type"`
+	Status  string   `json:"status"`
+	Version string   `json:"version,omitempty"`
+	Asset   string   `json:"asset,omitempty"`
+	Path    string   `json:"path,omitempty"`
+	Files   []string `json:"files,omitempty"`
+	Size    int64    `json:"size,omitempty"`
+	Hash    string   `json:"hash,omitempty"`
+	SHA256  string   `json:"sha256,omitempty"`
+	Reason  string   `json:"reason,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+type InstallReport map[string]ReportEntry
+type VendorEntry struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	File    string `json:"file"`
+	SHA256  string `json:"sha256"`
+}
+type VendorManifest map[string]VendorEntry
+type hashCacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+	SHA256  string `json:"sha256"`
+}
+type hashCache map[string]hashCacheEntry
+type kindError struct {
+	kind error
+	msg  string
+}
+type EventKind int
+type Event struct {
+	Kind       EventKind
+	Dependency string
+	Version    string
+	URL        string
+	Path       string
+	Bytes      int64
+	Total      int64
+	Err        error
+}
+
+// EventHandler lets a program embedding the Manager follow its progress.
+// HandleEvent is called from the goroutine doing the work.
+type EventHandler interface {
+	HandleEvent(Event)
+}
+type EventHandlerFunc func(Event)
+type progressReader struct {
+	reader io.Reader
+	emit   func(bytes int64)
+	bytes  int64
+	last   time.Time
+}
+type dependencyKey struct{}
+type checksumKey struct{}
+type CycloneDXBOM struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    CycloneDXMetadata    `json:"metadata"`
+	Components  []CycloneDXComponent `json:"components"`
+}
+type CycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     CycloneDXTools     `json:"tools"`
+	Component CycloneDXComponent `json:"component"`
+}
+type CycloneDXTools struct {
+	Components []CycloneDXComponent `json:"components"`
+}
+type CycloneDXComponent struct {
+	Type               string               `json:"type"`
+	BOMRef             string               `json:"bom-ref,omitempty"`
+	Name               string               `json:"name"`
+	Version            string               `json:"version,omitempty"`
+	PURL               string               `json:"purl,omitempty"`
+	Hashes             []CycloneDXHash      `json:"hashes,omitempty"`
+	ExternalReferences []CycloneDXReference `json:"externalReferences,omitempty"`
+	Properties         []CycloneDXProperty  `json:"properties,omitempty"`
+	Components         []CycloneDXComponent `json:"components,omitempty"`
+}
+type CycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+type CycloneDXReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+type SPDXDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      SPDXCreationInfo   `json:"creationInfo"`
+	Packages          []SPDXPackage      `json:"packages"`
+	Relationships     []SPDXRelationship `json:"relationships"`
+}
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+type SPDXPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Checksums        []SPDXChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []SPDXExternalRef `json:"externalRefs,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+}
+type SPDXChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+type SPDXExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+type SPDXRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+type hostLimitTransport struct {
+	// Slots are held until the response body is closed, so long downloads
+	// count against the per-host limit.
+	base   http.RoundTripper
+	limits map[string]int
+	limit  int
+	mu     sync.Mutex
+	slots  map[string]chan struct{}
+}
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+type LockFile map[string]LockDependency
+type LockDocument struct {
+	SchemaVersion int      `json:"schema_version"`
+	Dependencies  LockFile `json:"dependencies"`
+}
+
+const (
+	DepsFileName      = "fracture.json"
+	LockFileName      = "fracture-lock.json"
+	LockSchemaVersion = 1
+	VendorDirName     = "vendor"
+
+	defaultGitHubAPIVersion = "2022-11-28"
+
+	defaultConcurrencyPerHost   = 6
+	defaultGitHubAPIConcurrency = 2
+	defaultMaxRedirects         = 10
+
+	mediaTypeGitHubJSON = "application/vnd.github+json"
+	mediaTypeBinary     = "application/octet-stream"
+)
+
+var errUpdateDeclined = errors.New("update declined")
+
+// Failures callers may want to tell apart; match them with errors.Is.
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrPrivateNoToken   = errors.New("private repository requires a token")
+)
+
+// Events reported to Options.Events. A download sends EventDownloadProgress
+// as it goes and once more with Bytes equal to Total when it completes;
+// Total is -1 until then if the server sent no Content-Length.
+const (
+	EventResolveStarted EventKind = iota
+	EventDownloadProgress
+	EventExtract
+	EventInstalled
+	EventFailed
+)
+
+// githubTokenVars are read in order; the first one that is set wins.
+var githubTokenVars = []string{"FRACTURE_GITHUB_PAT", "GITHUB_TOKEN", "GH_TOKEN"}
+
+// Options configures a Manager. OptionsFromEnv fills in the settings that
+// come from FRACTURE_* environment variables; the rest default to zero.
+// Everything the Manager prints goes to Out, os.Stdout when nil; with
+// SummaryOnly only the closing summaries and reports are written. Version
+// is reported in the User-Agent, SBOMs and by self-update ("dev" when
+// empty), and Interactive allows prompting on stdin.
+type Options struct {
+	ConfigPath            string
+	LockPath              string
+	InsecureSkipTLSVerify bool
+	Offline               bool
+	Timeout               time.Duration
+	Select                bool
+	Replace               map[string]string
+	Output                string
+	Report                string
+	OnlyMissing           bool
+	ConcurrencyPerHost    int
+	NoLock                bool
+	Yes                   bool
+	TmpDir                string
+	AllowSystemPackages   bool
+	PruneLock             bool
+	PruneFiles            bool
+	AllowInsecureHTTP     bool
+	Changelog             bool
+	Verbose               bool
+	MaxRedirects          *int
+	Extract               *bool
+	AllowedHosts          []string
+	GitHubToken           string
+	GitHubTokenEnv        string
+	Tokens                map[string]string
+	UserAgent             string
+	APIVersion            *string
+	CacheDir              string
+	CABundle              string
+	Events                EventHandler
+	Out                   io.Writer
+	SummaryOnly           bool
+	Version               string
+	Interactive           bool
+}
+
+type Manager struct {
+	workDir       string
+	githubToken   string
+	githubEnv     string
+	tokens        map[string]string
+	userAgent     string
+	apiVersion    string
+	configPath    string
+	lockPath      string
+	overridePath  string
+	exportPath    string
+	vendorPath    string
+	vendor        VendorManifest
+	reportPath    string
+	report        InstallReport
+	replace       map[string]string
+	cacheDir      string
+	offline       bool
+	onlyMissing   bool
+	noLock        bool
+	assumeYes     bool
+	updateLock    LockFile
+	installLock   LockFile
+	probeLock     LockFile
+	lockMigrated  *int
+	timeout       time.Duration
+	interactive   bool
+	tmpRoot       string
+	allowPackages bool
+	pruneLock     bool
+	pruneFiles    bool
+	extract       *bool
+	allowHTTP     bool
+	allowedHosts  []string
+	changelog     bool
+	trace         *decisionTrace
+	events        EventHandler
+	httpClient    *http.Client
+	out           io.Writer
+	progress      io.Writer
+	version       string
+	selectAsset   bool
+}
+
+func OptionsFromEnv() (Options, error) {
+	opts := Options{
+		InsecureSkipTLSVerify: isTruthyEnv("FRACTURE_INSECURE_SKIP_TLS_VERIFY"),
+		TmpDir:                os.Getenv("FRACTURE_TMPDIR"),
+		AllowedHosts:          ParseHostList(os.Getenv("FRACTURE_ALLOWED_HOSTS")),
+		UserAgent:             os.Getenv("FRACTURE_USER_AGENT"),
+		CacheDir:              os.Getenv("FRACTURE_CACHE_DIR"),
+		CABundle:              os.Getenv("FRACTURE_CA_BUNDLE"),
+		Tokens:                make(map[string]string),
+	}
+	// GITHUB_TOKEN and GH_TOKEN are what GitHub Actions and the gh CLI set,
+	// so CI works without exporting FRACTURE_GITHUB_PAT.
+	for _, name := range githubTokenVars {
+		if token := os.Getenv(name); token != "" {
+			opts.GitHubToken, opts.GitHubTokenEnv = token, name
+			break
+		}
+	}
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if (strings.HasPrefix(name, "FRACTURE_TOKEN_") || name == "FRACTURE_GITLAB_PAT") && value != "" {
+			opts.Tokens[name] = value
+		}
+	}
+	if apiVersion, set := os.LookupEnv("FRACTURE_GITHUB_API_VERSION"); set {
+		opts.APIVersion = &apiVersion
+	}
+	if value := os.Getenv("FRACTURE_MAX_REDIRECTS"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return opts, fmt.Errorf("invalid FRACTURE_MAX_REDIRECTS value %q, expected a number", value)
+		}
+		opts.MaxRedirects = &limit
+	}
+	return opts, nil
+}
+func NewManager(opts Options) (*Manager, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %v", err)
+	}
+	configPath := opts.ConfigPath
+	if configPath == "" {
+		configPath = DepsFileName
+		wd = findProjectDir(wd)
+	}
+	lockPath := generateLockFileName(configPath)
+	if opts.LockPath != "" {
+		// Relative to where fracture runs, like -c, not to the project
+		// directory found above it.
+		lockPath, err = filepath.Abs(opts.LockPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve lock path: %v", err)
+		}
+	} else if configured := configuredLockPath(wd, configPath); configured != "" {
+		lockPath = configured
+	}
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	progress := out
+	if opts.SummaryOnly {
+		progress = io.Discard
+	}
+	version := opts.Version
+	if version == "" {
+		version = "dev"
+	}
+	httpClient, err := newHTTPClient(opts, progress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure HTTP client: %v", err)
+	}
+	githubEnv := opts.GitHubTokenEnv
+	if githubEnv == "" {
+		githubEnv = githubTokenVars[0]
+	}
+	userAgent := opts.UserAgent
+	if userAgent == "" {
+		userAgent = "fracture/" + version
+	}
+	apiVersion := defaultGitHubAPIVersion
+	if opts.APIVersion != nil {
+		apiVersion = *opts.APIVersion
+	}
+	cacheDir := opts.CacheDir
+	if cacheDir == "" {
+		cacheDir = defaultCacheDir()
+	}
+	tmpRoot := opts.TmpDir
+	if tmpRoot == "" {
+		tmpRoot = os.TempDir()
+	}
+
+	return &Manager{
+		workDir:       wd,
+		githubToken:   opts.GitHubToken,
+		githubEnv:     githubEnv,
+		tokens:        opts.Tokens,
+		userAgent:     userAgent,
+		apiVersion:    apiVersion,
+		configPath:    configPath,
+		lockPath:      lockPath,
+		overridePath:  generateOverrideFileName(configPath),
+		exportPath:    opts.Output,
+		vendorPath:    generateVendorManifestName(configPath),
+		reportPath:    opts.Report,
+		replace:       opts.Replace,
+		cacheDir:      cacheDir,
+		offline:       opts.Offline,
+		onlyMissing:   opts.OnlyMissing,
+		noLock:        opts.NoLock,
+		assumeYes:     opts.Yes,
+		allowPackages: opts.AllowSystemPackages,
+		pruneLock:     opts.PruneLock || opts.PruneFiles,
+		pruneFiles:    opts.PruneFiles,
+		extract:       opts.Extract,
+		allowHTTP:     opts.AllowInsecureHTTP,
+		allowedHosts:  opts.AllowedHosts,
+		changelog:     opts.Changelog,
+		timeout:       opts.Timeout,
+		interactive:   opts.Interactive,
+		selectAsset:   opts.Select,
+		tmpRoot:       tmpRoot,
+		httpClient:    httpClient,
+		events:        opts.Events,
+		out:           out,
+		progress:      progress,
+		version:       version,
+	}, nil
+}
+func configuredLockPath(wd, configPath string) string {
+	// Read up front because some commands, like list, only open the lock
+	// file; a config that fails to parse is reported when it is loaded.
+	configFile := configPath
+	if !filepath.IsAbs(configFile) {
+		configFile = filepath.Join(wd, configFile)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return ""
+	}
+	var config ConfigFile
+	if decodeConfigJSON(data, &config) != nil || config.Lock == "" {
+		return ""
+	}
+	lockPath := expandEnvVars(config.Lock)
+	if filepath.IsAbs(lockPath) {
+		return lockPath
+	}
+	return filepath.Join(filepath.Dir(configPath), lockPath)
+}
+func findProjectDir(dir string) string {
+	// Like git looking for .git: the nearest directory with a fracture.json
+	// is the project root, so fracture works from any subdirectory.
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, DepsFileName)); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+func defaultCacheDir() string {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(userCacheDir, "fracture")
+}
+func newHTTPClient(opts Options, progress io.Writer) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSHandshakeTimeout = 30 * time.Second
+	transport.ResponseHeaderTimeout = 60 * time.Second
+	transport.TLSClientConfig = &tls.Config{}
+
+	if opts.CABundle != "" {
+		rootCAs, err := loadCABundle(opts.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.RootCAs = rootCAs
+	}
+
+	if opts.InsecureSkipTLSVerify {
+		fmt.Fprintln(progress, "⚠️  WARNING: TLS certificate verification is DISABLED. Connections are vulnerable to interception.")
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+
+	limited := &hostLimitTransport{
+		base:   transport,
+		limits: map[string]int{"api.github.com": defaultGitHubAPIConcurrency},
+		limit:  defaultConcurrencyPerHost,
+		slots:  make(map[string]chan struct{}),
+	}
+	if opts.ConcurrencyPerHost > 0 {
+		limited.limits = nil
+		limited.limit = opts.ConcurrencyPerHost
+	}
+	maxRedirects := defaultMaxRedirects
+	if opts.MaxRedirects != nil {
+		maxRedirects = *opts.MaxRedirects
+	}
+
+	client := &http.Client{Transport: limited}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects (see --max-redirects)", maxRedirects)
+		}
+		previous := via[len(via)-1]
+		if !opts.AllowInsecureHTTP && req.URL.Scheme == "http" && previous.URL.Scheme == "https" {
+			return fmt.Errorf("refusing redirect from HTTPS to plain HTTP %s; pass --allow-insecure-http to allow it", redactURL(req.URL))
+		}
+		if !hostAllowed(opts.AllowedHosts, req.URL.Hostname()) {
+			return fmt.Errorf("refusing redirect to %s, host %s is not allowed", redactURL(req.URL), req.URL.Hostname())
+		}
+		var dropped []string
+		if req.URL.Host != via[0].URL.Host {
+			dropped = dropCredentialHeaders(req.Header)
+		}
+		if opts.Verbose {
+			note := ""
+			if len(dropped) > 0 {
+				note = fmt.Sprintf(" (dropped %s)", strings.Join(dropped, ", "))
+			}
+			fmt.Fprintf(progress, "↪️  Redirect %d: %s -> %s%s\n", len(via), redactURL(previous.URL), redactURL(req.URL), note)
+		}
+		return nil
+	}
+	return client, nil
+}
+func ParseHostList(value string) []string {
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+func hostAllowed(allowed []string, host string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, entry := range allowed {
+		if host == entry || strings.HasSuffix(host, "."+entry) || canonicalHost(host) == entry {
+			return true
+		}
+		// github.com serves release assets and raw files from its
+		// githubusercontent.com storage hosts.
+		if entry == "github.com" && (host == "githubusercontent.com" || strings.HasSuffix(host, ".githubusercontent.com")) {
+			return true
+		}
+	}
+	return false
+}
+func dropCredentialHeaders(header http.Header) []string {
+	// Tokens must never follow a redirect to another host, e.g. from
+	// api.github.com to the storage host serving the asset. Configured
+	// headers may carry credentials under any name, so only headers known
+	// to be harmless are kept.
+	var dropped []string
+	for name := range header {
+		switch name {
+		case "Accept", "Accept-Encoding", "User-Agent", "X-Github-Api-Version", "Range":
+			continue
+		}
+		header.Del(name)
+		dropped = append(dropped, name)
+	}
+	sort.Strings(dropped)
+	return dropped
+}
+func redactURL(u *url.URL) string {
+	// Redirect targets are often pre-signed, and the query carries the
+	// signature, so it is left out of messages.
+	redacted := *u
+	redacted.User = nil
+	if redacted.RawQuery != "" {
+		redacted.RawQuery = "…"
+	}
+	return redacted.String()
+}
+func (t *hostLimitTransport) hostSlots(host string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	slots, exists := t.slots[host]
+	if !exists {
+		limit := t.limit
+		if hostLimit, found := t.limits[host]; found {
+			limit = hostLimit
+		}
+		slots = make(chan struct{}, limit)
+		t.slots[host] = slots
+	}
+	return slots
+}
+func (t *hostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	slots := t.hostSlots(req.URL.Hostname())
+	select {
+	case slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	release := func() { <-slots }
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %s: %v", path, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid PEM certificates found in CA bundle %s", path)
+	}
+
+	return pool, nil
+}
+func FindConfigFiles(configDir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(configDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var configPaths []string
+	for _, match := range matches {
+		if strings.HasSuffix(match, "-lock.json") || strings.HasSuffix(match, ".override.json") || strings.HasSuffix(match, "-export.json") {
+			continue
+		}
+		configPaths = append(configPaths, match)
+	}
+	if len(configPaths) == 0 {
+		return nil, fmt.Errorf("no config files found in %s", configDir)
+	}
+
+	sort.Strings(configPaths)
+	return configPaths, nil
+}
+func generateLockFileName(configPath string) string {
+	ext := filepath.Ext(configPath)
+	nameWithoutExt := strings.TrimSuffix(configPath, ext)
+	return nameWithoutExt + "-lock.json"
+}
+func generateExportFileName(configPath string) string {
+	ext := filepath.Ext(configPath)
+	nameWithoutExt := strings.TrimSuffix(configPath, ext)
+	return nameWithoutExt + "-export.json"
+}
+func generateVendorManifestName(configPath string) string {
+	ext := filepath.Ext(configPath)
+	name := strings.TrimSuffix(filepath.Base(configPath), ext)
+	return filepath.Join(filepath.Dir(configPath), VendorDirName, name+"-manifest.json")
+}
+func generateOverrideFileName(configPath string) string {
+	ext := filepath.Ext(configPath)
+	nameWithoutExt := strings.TrimSuffix(configPath, ext)
+	return nameWithoutExt + ".override.json"
+}
+func (pm *Manager) loadDepsFile() (DepsFile, error) {
+	depsPath := filepath.Join(pm.workDir, pm.configPath)
+	data, err := os.ReadFile(depsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config ConfigFile
+	err = decodeConfigJSON(data, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := config.Dependencies
+	dir := ""
+	if config.Defaults != nil {
+		dir = expandEnvVars(config.Defaults.Dir)
+	}
+	for name, dep := range deps {
+		if unset := unsetEnvVars(dep.Source); len(unset) > 0 {
+			return nil, fmt.Errorf("dependency %s: source %s uses unset environment variable %s", name, dep.Source, strings.Join(unset, ", "))
+		}
+		dep = expandDependencyEnv(dep)
+		if dir != "" && dep.Path != "" && !filepath.IsAbs(dep.Path) && !strings.HasPrefix(dep.Path, "~") {
+			dep.Path = path.Join(dir, dep.Path)
+		}
+		dep.Path, err = expandHomeDir(dep.Path)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %s: %v", name, err)
+		}
+		deps[name] = dep
+	}
+	for _, name := range sortedDependencyNames(deps) {
+		if deps[name].LegacyName != "" {
+			fmt.Fprintf(pm.progress, "⚠️  Warning: dependency %s: \"name\" is ignored and will be rejected by a later release, remove it from %s\n", name, pm.configPath)
+		}
+	}
+
+	err = pm.applyOverrides(deps)
+	if err != nil {
+		return nil, err
+	}
+	pm.applyExtractOverride(deps)
+	// Checked after overrides and --replace, since those decide where
+	// downloads actually come from.
+	for _, name := range sortedDependencyNames(deps) {
+		dep := deps[name]
+		for _, source := range append([]string{dep.Source}, dep.Mirrors...) {
+			if host := sourceHost(source); host != "" && !hostAllowed(pm.allowedHosts, host) {
+				return nil, fmt.Errorf("dependency %s: host %s of %s is not allowed (allowed hosts: %s)", name, host, source, strings.Join(pm.allowedHosts, ", "))
+			}
+		}
+	}
+	return deps, nil
+}
+func (pm *Manager) applyExtractOverride(deps DepsFile) {
+	// --extract and --no-extract win over every dependency's extract for
+	// the run; repositories and system packages are never archives.
+	if pm.extract == nil {
+		return
+	}
+	for name, dep := range deps {
+		if pm.dependencyType(name, dep) == "repository" || dep.InstallPackage {
+			continue
+		}
+		dep.Extract = *pm.extract
+		if !dep.Extract {
+			dep.Flatten = false
+		}
+		deps[name] = dep
+	}
+}
+func (pm *Manager) loadOverridesFile() (OverridesFile, error) {
+	overrides := make(OverridesFile)
+	data, err := os.ReadFile(filepath.Join(pm.workDir, pm.overridePath))
+	if os.IsNotExist(err) {
+		return overrides, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = decodeConfigJSON(data, &overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", pm.overridePath, err)
+	}
+	return overrides, nil
+}
+func (pm *Manager) applyOverrides(deps DepsFile) error {
+	overrides, err := pm.loadOverridesFile()
+	if err != nil {
+		return err
+	}
+	for name, source := range pm.replace {
+		overrides[name] = Override{Source: source}
+	}
+
+	for name, override := range overrides {
+		dep, exists := deps[name]
+		if !exists {
+			return fmt.Errorf("override for unknown dependency %s", name)
+		}
+		if override.Source == "" {
+			continue
+		}
+		if unset := unsetEnvVars(override.Source); len(unset) > 0 {
+			return fmt.Errorf("override for %s uses unset environment variable %s", name, strings.Join(unset, ", "))
+		}
+		source := expandPlatformVars(expandEnvVars(override.Source))
+		fmt.Fprintf(pm.progress, "🔀 Replacing source of %s: %s -> %s\n", name, dep.Source, source)
+		dep.Source = source
+		deps[name] = dep
+	}
+	return nil
+}
+func (c *ConfigFile) UnmarshalJSON(data []byte) error {
+	if !isStructuredConfig(data) {
+		// The original format: a flat map of dependency names.
+		c.Defaults = nil
+		return decodeStrictJSON(data, &c.Dependencies)
+	}
+
+	var config struct {
+		Defaults     *ConfigDefaults `json:"defaults"`
+		Lock         string          `json:"lock"`
+		Dependencies DepsFile        `json:"dependencies"`
+	}
+	err := decodeStrictJSON(data, &config)
+	if err != nil {
+		return err
+	}
+	c.Defaults = config.Defaults
+	c.Lock = config.Lock
+	c.Dependencies = config.Dependencies
+	if c.Defaults == nil {
+		return nil
+	}
+	if c.Defaults.Path != "" || c.Defaults.Source != "" || c.Defaults.Version != "" {
+		return errors.New("defaults cannot set path, source or version")
+	}
+
+	// Each dependency is decoded over a fresh copy of the defaults, so only
+	// the fields it sets, including explicit false or empty values, win.
+	var raw struct {
+		Defaults     json.RawMessage            `json:"defaults"`
+		Dependencies map[string]json.RawMessage `json:"dependencies"`
+	}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	for name, value := range raw.Dependencies {
+		var dep Dependency
+		err = json.Unmarshal(raw.Defaults, &dep)
+		if err == nil {
+			err = json.Unmarshal(value, &dep)
+		}
+		if err != nil {
+			return err
+		}
+		c.Dependencies[name] = dep
+	}
+	return nil
+}
+func isStructuredConfig(data []byte) bool {
+	// A dependency always has a source, which tells a legacy dependency
+	// named "defaults" or "dependencies" apart from the structured sections.
+	var raw map[string]json.RawMessage
+	if json.Unmarshal(data, &raw) != nil {
+		return false
+	}
+	for _, key := range []string{"defaults", "dependencies"} {
+		value, exists := raw[key]
+		if !exists {
+			continue
+		}
+		var section map[string]json.RawMessage
+		if json.Unmarshal(value, &section) != nil {
+			continue
+		}
+		if _, hasSource := section["source"]; !hasSource {
+			return true
+		}
+	}
+	return false
+}
+func decodeStrictJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+func decodeConfigJSON(data []byte, v interface{}) error {
+	// Offsets from a custom UnmarshalJSON are relative to the start of the
+	// value, so leading whitespace is trimmed and added back to offsets.
+	standardized := standardizeJSON(data)
+	trimmed := bytes.TrimLeft(standardized, " \t\r\n")
+	lead := int64(len(standardized) - len(trimmed))
+	err := decodeStrictJSON(trimmed, v)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return jsonErrorAt(data, lead+syntaxErr.Offset-1, syntaxErr.Error())
+	case errors.As(err, &typeErr):
+		message := fmt.Sprintf("%s must be %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		return jsonErrorAt(data, lead+typeErr.Offset-1, message)
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("config is empty")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		offset := jsonKeyOffset(data, field)
+		message := "unknown field " + field
+		keys := keyPathAt(data, offset)
+		if len(keys) > 0 && (keys[0] == "defaults" || keys[0] == "dependencies") && isStructuredConfig(trimmed) {
+			if keys[0] == "defaults" {
+				keys = nil
+				message = "defaults: unknown field " + field
+			} else {
+				keys = keys[1:]
+			}
+		}
+		if len(keys) > 0 {
+			message = fmt.Sprintf("dependency %s: unknown field %s", keys[0], field)
+		}
+		return jsonErrorAt(data, offset, message)
+	}
+	return err
+}
+func jsonKeyOffset(data []byte, quotedKey string) int64 {
+	pattern := regexp.MustCompile(regexp.QuoteMeta(quotedKey) + `\s*:`)
+	location := pattern.FindIndex(standardizeJSON(data))
+	if location == nil {
+		return -1
+	}
+	return int64(location[0])
+}
+func keyPathAt(data []byte, offset int64) []string {
+	if offset < 0 {
+		return nil
+	}
+	return objectKeyPath(standardizeJSON(data), 0, offset)
+}
+func objectKeyPath(data []byte, base, offset int64) []string {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		err = decoder.Decode(&value)
+		if err != nil {
+			return []string{key}
+		}
+		end := base + decoder.InputOffset()
+		if end > offset {
+			start := end - int64(len(value))
+			if offset < start {
+				// The offset is on this object's own key.
+				return nil
+			}
+			return append([]string{key}, objectKeyPath(value, start, offset)...)
+		}
+	}
+	return nil
+}
+func jsonErrorAt(data []byte, offset int64, message string) error {
+	if offset < 0 || offset >= int64(len(data)) {
+		return errors.New(message)
+	}
+
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	lineEnd := bytes.IndexByte(data[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(data)
+	} else {
+		lineEnd += int(offset)
+	}
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	column := int(offset) - lineStart + 1
+
+	snippet := strings.TrimRight(string(data[lineStart:lineEnd]), "\r")
+	caret := strings.Repeat(" ", column-1) + "^"
+	return fmt.Errorf("line %d, column %d: %s\n    %s\n    %s", line, column, message, strings.ReplaceAll(snippet, "\t", " "), caret)
+}
+func expandDependencyEnv(dep Dependency) Dependency {
+	dep.Path = expandEnvVars(dep.Path)
+	dep.Source = expandPlatformVars(expandEnvVars(dep.Source))
+	dep.Type = expandEnvVars(dep.Type)
+	dep.AssetSuffix = expandEnvVars(dep.AssetSuffix)
+	dep.AssetOS = expandEnvVars(dep.AssetOS)
+	dep.AssetArch = expandEnvVars(dep.AssetArch)
+	dep.Filename = expandEnvVars(dep.Filename)
+	dep.AssetName = expandEnvVars(dep.AssetName)
+	dep.AssetExtension = expandEnvVars(dep.AssetExtension)
+	dep.Version = expandEnvVars(dep.Version)
+	for i, mirror := range dep.Mirrors {
+		dep.Mirrors[i] = expandPlatformVars(expandEnvVars(mirror))
+	}
+	for name, value := range dep.Headers {
+		dep.Headers[name] = expandEnvVars(value)
+	}
+	return dep
+}
+func expandHomeDir(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+	name, rest, _ := strings.Cut(filepath.ToSlash(path[1:]), "/")
+	var home string
+	if name == "" {
+		var err error
+		home, err = os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot expand ~ in %s: %v", path, err)
+		}
+	} else {
+		account, err := user.Lookup(name)
+		if err != nil {
+			return "", fmt.Errorf("cannot expand ~%s in %s: %v", name, path, err)
+		}
+		home = account.HomeDir
+	}
+	return filepath.Join(home, filepath.FromSlash(rest)), nil
+}
+func standardizeJSON(data []byte) []byte {
+	return removeTrailingCommas(stripJSONComments(data))
+}
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+	inString := false
+
+	for i := 0; i < len(out); i++ {
+		if inString {
+			if out[i] == '\\' {
+				i++
+			} else if out[i] == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		if out[i] == '"' {
+			inString = true
+		} else if out[i] == '/' && i+1 < len(out) && out[i+1] == '/' {
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		} else if out[i] == '/' && i+1 < len(out) && out[i+1] == '*' {
+			for ; i < len(out); i++ {
+				closing := out[i] == '*' && i+1 < len(out) && out[i+1] == '/'
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+				if closing {
+					out[i+1] = ' '
+					i++
+					break
+				}
+			}
+		}
+	}
+
+	return out
+}
+func removeTrailingCommas(data []byte) []byte {
+	inString := false
+
+	for i := 0; i < len(data); i++ {
+		if inString {
+			if data[i] == '\\' {
+				i++
+			} else if data[i] == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		if data[i] == '"' {
+			inString = true
+		} else if data[i] == ',' {
+			j := i + 1
+			for j < len(data) && (data[j] == ' ' || data[j] == '\t' || data[j] == '\n' || data[j] == '\r') {
+				j++
+			}
+			if j < len(data) && (data[j] == '}' || data[j] == ']') {
+				data[i] = ' '
+			}
+		}
+	}
+
+	return data
+}
+func (pm *Manager) loadLockFile() (LockFile, error) {
+	lockPath := pm.targetPath(pm.lockPath)
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return make(LockFile), nil
+	}
+
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return make(LockFile), nil
+	}
+	version := 0
+	if value, ok := raw["schema_version"]; ok && json.Unmarshal(value, &version) != nil {
+		// A number marks a versioned lock file; anything else is a
+		// dependency named "schema_version" in a bare map.
+		version = 0
+	}
+	if version > LockSchemaVersion {
+		return nil, fmt.Errorf("lock schema version %d is newer than this fracture supports (%d), run 'fracture self-update'", version, LockSchemaVersion)
+	}
+	lock, err := migrateLockFile(data, version)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read lock schema version %d: %v", version, err)
+	}
+	if version < LockSchemaVersion {
+		pm.lockMigrated = &version
+	}
+	return lock, nil
+}
+func migrateLockFile(data []byte, version int) (LockFile, error) {
+	// Each step upgrades the document by one schema version, so older
+	// lock files pass through every migration in turn.
+	var doc LockDocument
+	for ; version < LockSchemaVersion; version++ {
+		switch version {
+		case 0:
+			// Version 0 is the original bare map of dependency names.
+			var deps LockFile
+			err := json.Unmarshal(data, &deps)
+			if err != nil {
+				return nil, err
+			}
+			doc = LockDocument{SchemaVersion: 1, Dependencies: deps}
+			data, err = json.Marshal(doc)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Dependencies == nil {
+		doc.Dependencies = make(LockFile)
+	}
+	return doc.Dependencies, nil
+}
+func (pm *Manager) saveLockFile(lock LockFile) error {
+	lockPath := pm.targetPath(pm.lockPath)
+	data, err := json.MarshalIndent(LockDocument{SchemaVersion: LockSchemaVersion, Dependencies: lock}, "", "  ")
+	if err != nil {
+		return err
+	}
+	// A lock moved with --lock or "lock" may live in a build directory
+	// that does not exist yet.
+	err = os.MkdirAll(filepath.Dir(lockPath), 0755)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(lockPath, data, 0644)
+	if err == nil && pm.lockMigrated != nil {
+		fmt.Fprintf(pm.progress, "📝 Upgraded %s from lock schema version %d to %d\n", pm.lockPath, *pm.lockMigrated, LockSchemaVersion)
+		pm.lockMigrated = nil
+	}
+	return err
+}
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	closeErr := tmpFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+func (pm *Manager) extractRepoInfo(source string) (string, string, error) {
+	re := regexp.MustCompile(`github\.com/([^/]+)/([^/]+)(?:\.git)?`)
+	matches := re.FindStringSubmatch(source)
+	if len(matches) < 3 {
+		return "", "", fmt.Errorf("invalid GitHub URL format: %s", source)
+	}
+	return matches[1], strings.TrimSuffix(matches[2], ".git"), nil
+}
+func (pm *Manager) newRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !hostAllowed(pm.allowedHosts, req.URL.Hostname()) {
+		return nil, fmt.Errorf("host %s is not allowed (allowed hosts: %s)", req.URL.Hostname(), strings.Join(pm.allowedHosts, ", "))
+	}
+	req.Header.Set("User-Agent", pm.userAgent)
+	if req.URL.Host == "api.github.com" && pm.apiVersion != "" {
+		req.Header.Set("X-GitHub-Api-Version", pm.apiVersion)
+	}
+	return req, nil
+}
+func (pm *Manager) createAuthenticatedRequest(ctx context.Context, method, url, accept string) (*http.Request, error) {
+	req, err := pm.newRequest(ctx, method, url)
+	if err != nil {
+		return nil, err
+	}
+	if token, _ := pm.credentialFor(req.URL.Hostname()); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", accept)
+
+	return req, nil
+}
+func (pm *Manager) getLatestRelease(ctx context.Context, owner, repo string, isPrivate bool) (*GitHubRelease, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", owner, repo)
+	return pm.getRelease(ctx, url, owner, repo, isPrivate, fmt.Sprintf("repository %s/%s not found or no access", owner, repo))
+}
+func (pm *Manager) getReleaseByTag(ctx context.Context, owner, repo, tag string, isPrivate bool) (*GitHubRelease, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/tags/%s", owner, repo, tag)
+	return pm.getRelease(ctx, url, owner, repo, isPrivate, fmt.Sprintf("release %s not found in %s/%s or no access", tag, owner, repo))
+}
+func (pm *Manager) getRelease(ctx context.Context, url, owner, repo string, isPrivate bool, notFound string) (*GitHubRelease, error) {
+	if pm.offline {
+		return nil, fmt.Errorf("cannot query releases for %s/%s in offline mode", owner, repo)
+	}
+	if isPrivate && pm.githubToken == "" {
+		return nil, kindErrorf(ErrPrivateNoToken, "private repository %s/%s requires %s", owner, repo, strings.Join(githubTokenVars, ", "))
+	}
+
+	req, err := pm.createAuthenticatedRequest(ctx, "GET", url, mediaTypeGitHubJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := pm.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, kindErrorf(ErrNotFound, "%s", notFound)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, statusError(resp, fmt.Sprintf("GitHub API returned status %d", resp.StatusCode))
+	}
+
+	var release GitHubRelease
+	err = json.NewDecoder(resp.Body).Decode(&release)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub API response: %v", err)
+	}
+
+	return &release, nil
+}
+func (pm *Manager) resolveRelease(ctx context.Context, owner, repo string, dep Dependency) (*GitHubRelease, error) {
+	if dep.Version != "" {
+		release, err := pm.getReleaseByTag(ctx, owner, repo, dep.Version, dep.Private)
+		if err != nil {
+			return nil, err
+		}
+		pm.trace.record("release: %s (pinned by version in config)", release.TagName)
+		return release, nil
+	}
+	if dep.Prerelease && dep.Resolve != "semver" {
+		return pm.newestRelease(ctx, owner, repo, dep)
+	}
+	if dep.TagPrefix == "" && dep.Resolve != "semver" {
+		release, err := pm.getLatestRelease(ctx, owner, repo, dep.Private)
+		if err != nil {
+			return nil, err
+		}
+		pm.trace.record("release: %s (latest release of %s/%s)", release.TagName, owner, repo)
+		return release, nil
+	}
+
+	// /releases/latest ignores tag prefixes and follows whatever release the
+	// maintainers marked latest, so monorepo components and resolve: semver
+	// are picked from the release list by the highest version instead.
+	releases, err := pm.listReleases(ctx, owner, repo, dep.Private)
+	if err != nil {
+		return nil, err
+	}
+	var best *GitHubRelease
+	var bestVersion semver
+	for i := range releases {
+		release := &releases[i]
+		if release.Draft || (release.Prerelease && !dep.Prerelease) || !strings.HasPrefix(release.TagName, dep.TagPrefix) {
+			continue
+		}
+		version, err := parseSemver(strings.TrimPrefix(release.TagName, dep.TagPrefix))
+		if err != nil {
+			pm.trace.record("skipped tag %s: %v", release.TagName, err)
+			continue
+		}
+		if best == nil || compareSemver(version, bestVersion) > 0 {
+			best, bestVersion = release, version
+		}
+	}
+	if best == nil && dep.TagPrefix == "" {
+		return nil, fmt.Errorf("no release of %s/%s has a semantic version tag", owner, repo)
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no release of %s/%s has a tag starting with %q followed by a version", owner, repo, dep.TagPrefix)
+	}
+	if dep.TagPrefix == "" {
+		pm.trace.record("release: %s (highest version in %s/%s, resolve: semver)", best.TagName, owner, repo)
+		return best, nil
+	}
+	pm.trace.record("release: %s (highest version tagged %s* in %s/%s)", best.TagName, dep.TagPrefix, owner, repo)
+	return best, nil
+}
+func (pm *Manager) newestRelease(ctx context.Context, owner, repo string, dep Dependency) (*GitHubRelease, error) {
+	// /releases/latest never returns pre-releases, so projects that only
+	// publish betas are resolved from the list by publish time.
+	releases, err := pm.listReleases(ctx, owner, repo, dep.Private)
+	if err != nil {
+		return nil, err
+	}
+	var newest *GitHubRelease
+	for i := range releases {
+		release := &releases[i]
+		if release.Draft || !strings.HasPrefix(release.TagName, dep.TagPrefix) {
+			continue
+		}
+		// RFC 3339 timestamps in UTC sort as strings.
+		if newest == nil || release.PublishedAt > newest.PublishedAt {
+			newest = release
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("no published release found in %s/%s", owner, repo)
+	}
+	pm.trace.record("release: %s (newest release of %s/%s by publish time, pre-releases included)", newest.TagName, owner, repo)
+	return newest, nil
+}
+func (pm *Manager) listReleases(ctx context.Context, owner, repo string, isPrivate bool) ([]GitHubRelease, error) {
+	if pm.offline {
+		return nil, fmt.Errorf("cannot query releases for %s/%s in offline mode", owner, repo)
+	}
+	if isPrivate && pm.githubToken == "" {
+		return nil, kindErrorf(ErrPrivateNoToken, "private repository %s/%s requires %s", owner, repo, strings.Join(githubTokenVars, ", "))
+	}
+
+	var releases []GitHubRelease
+	for page := 1; page <= 10; page++ {
+		batch, err := pm.listReleasePage(ctx, owner, repo, 100, page)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, batch...)
+		if len(batch) < 100 {
+			break
+		}
+	}
+	return releases, nil
+}
+func (pm *Manager) listReleasePage(ctx context.Context, owner, repo string, perPage, page int) ([]GitHubRelease, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases?per_page=%d&page=%d", owner, repo, perPage, page)
+	req, err := pm.createAuthenticatedRequest(ctx, "GET", url, mediaTypeGitHubJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := pm.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+		var batch []GitHubRelease
+		if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+			return nil, fmt.Errorf("failed to parse GitHub API response: %v", err)
+		}
+		return batch, nil
+	case 404:
+		return nil, kindErrorf(ErrNotFound, "repository %s/%s not found or no access", owner, repo)
+	default:
+		return nil, statusError(resp, fmt.Sprintf("GitHub API returned status %d", resp.StatusCode))
+	}
+}
+func assetAPIURL(owner, repo string, assetID int) string {
+	return fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/assets/%d", owner, repo, assetID)
+}
+func (pm *Manager) downloadAssetViaAPI(ctx context.Context, url, targetPath string, mode os.FileMode, headers map[string]string) error {
+	fmt.Fprintf(pm.progress, "Downloading via API: %s...\n", url)
+
+	req, err := pm.createAuthenticatedRequest(ctx, "GET", url, mediaTypeBinary)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	setHeaders(req, headers)
+
+	return pm.downloadToFile(req, targetPath, mode)
+}
+func (pm *Manager) downloadBinary(ctx context.Context, url, targetPath string, isPrivate bool, mode os.FileMode, headers map[string]string) error {
+	err := pm.checkInsecureURL(url)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(pm.progress, "Downloading %s...\n", url)
+
+	var req *http.Request
+	if isPrivate {
+		if token, envName := pm.credentialFor(sourceHost(url)); token == "" {
+			return kindErrorf(ErrPrivateNoToken, "private repository requires %s", envName)
+		}
+		req, err = pm.createAuthenticatedRequest(ctx, "GET", url, mediaTypeBinary)
+	} else {
+		req, err = pm.newRequest(ctx, "GET", url)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	setHeaders(req, headers)
+
+	return pm.downloadToFile(req, targetPath, mode)
+}
+func (pm *Manager) checkInsecureURL(rawURL string) error {
+	// Plain HTTP downloads can be swapped in transit, so they need an
+	// explicit opt-in and stay noisy even then.
+	if !strings.HasPrefix(strings.ToLower(rawURL), "http://") {
+		return nil
+	}
+	if !pm.allowHTTP {
+		return fmt.Errorf("refusing to download %s over plain HTTP, it can be tampered with in transit; use https or pass --allow-insecure-http", rawURL)
+	}
+	fmt.Fprintf(pm.progress, "⚠️  WARNING: %s uses plain HTTP, the download can be tampered with in transit\n", rawURL)
+	return nil
+}
+func setHeaders(req *http.Request, headers map[string]string) {
+	// Configured headers win over the defaults, so a dependency can bring
+	// its own Authorization scheme.
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+}
+func (pm *Manager) downloadResolved(ctx context.Context, resolved *ResolvedDependency, targetPath string, mode os.FileMode) error {
+	err := pm.downloadResolvedFile(ctx, resolved, targetPath, mode)
+	if err != nil {
+		return err
+	}
+	resolved.SHA256, err = fileSHA256(targetPath)
+	return err
+}
+func (pm *Manager) downloadResolvedFile(ctx context.Context, resolved *ResolvedDependency, targetPath string, mode os.FileMode) error {
+	// The expected checksum picks the cache entry, and the download is
+	// checked against it.
+	ctx = context.WithValue(ctx, checksumKey{}, resolved.expectedSHA256())
+	dep := resolved.Dependency
+	var err error
+	if dep.Private && resolved.Type == "binary" {
+		err = pm.downloadAssetViaAPI(ctx, resolved.DownloadURL, targetPath, mode, dep.Headers)
+	} else {
+		err = pm.downloadBinary(ctx, resolved.DownloadURL, targetPath, dep.Private, mode, dep.Headers)
+	}
+	if err == nil || len(dep.Mirrors) == 0 || ctx.Err() != nil {
+		return err
+	}
+
+	fileName := path.Base(resolved.DownloadURL)
+	if resolved.Asset != nil && resolved.Asset.Name != "" {
+		fileName = resolved.Asset.Name
+	} else if resolved.Type == "source" && archiveFormat(fileName) == "" {
+		// API tarball/zipball URLs end in the bare ref.
+		fileName += "." + resolved.SourceFormat
+	}
+	errs := []string{fmt.Sprintf("%s: %v", resolved.DownloadURL, err)}
+	for _, mirror := range dep.Mirrors {
+		mirrorURL := expandMirrorURL(mirror, resolved.Version, fileName)
+		fmt.Fprintf(pm.progress, "⚠️  Download failed (%v), trying mirror %s\n", err, mirrorURL)
+		err = pm.downloadBinary(ctx, mirrorURL, targetPath, false, mode, dep.Headers)
+		if err == nil {
+			resolved.Mirror = mirrorURL
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", mirrorURL, err))
+	}
+	return fmt.Errorf("all download URLs failed:\n  %s", strings.Join(errs, "\n  "))
+}
+func (r *ResolvedDependency) expectedSHA256() string {
+	if r.SHA256 != "" {
+		return r.SHA256
+	}
+	if r.Asset != nil {
+		if digest, ok := strings.CutPrefix(r.Asset.Digest, "sha256:"); ok {
+			return digest
+		}
+	}
+	return ""
+}
+func (r *ResolvedDependency) useLockedChecksums(lockDep LockDependency) {
+	// Only a lock entry for the same download says what it should contain.
+	if lockDep.URL != r.DownloadURL || !sameVersion(lockDep.Version, r.Version) {
+		return
+	}
+	r.SHA256 = lockDep.SHA256
+	for _, part := range r.Parts {
+		for _, asset := range lockDep.Assets {
+			if asset.URL == part.DownloadURL {
+				part.SHA256 = asset.SHA256
+			}
+		}
+	}
+}
+func expandMirrorURL(mirror, version, fileName string) string {
+	if !strings.Contains(mirror, "@VERSION") && !strings.Contains(mirror, "@ASSET_NAME") {
+		return strings.TrimRight(mirror, "/") + "/" + fileName
+	}
+	expanded := strings.ReplaceAll(mirror, "@VERSION", version)
+	return strings.ReplaceAll(expanded, "@ASSET_NAME", fileName)
+}
+func (pm *Manager) downloadToFile(req *http.Request, targetPath string, mode os.FileMode) error {
+	url := req.URL.String()
+	checksum, _ := req.Context().Value(checksumKey{}).(string)
+
+	err := os.MkdirAll(filepath.Dir(targetPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	if pm.restoreFromVendor(url, targetPath) {
+		fmt.Fprintf(pm.progress, "Using vendored copy of %s\n", url)
+	} else if pm.restoreFromCache(url, checksum, targetPath) {
+		fmt.Fprintf(pm.progress, "Using cached download for %s\n", url)
+	} else if pm.offline {
+		return fmt.Errorf("%s is not in the download cache (offline mode)", url)
+	} else {
+		resp, err := pm.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to download file: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != 200 {
+			return statusError(resp, fmt.Sprintf("server returned status %d", resp.StatusCode))
+		}
+
+		file, err := os.Create(targetPath)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %v", err)
+		}
+
+		depName, _ := req.Context().Value(dependencyKey{}).(string)
+		progress := func(bytes, total int64) {
+			pm.emit(Event{Kind: EventDownloadProgress, Dependency: depName, URL: redactURL(req.URL), Path: targetPath, Bytes: bytes, Total: total})
+		}
+		body := &progressReader{reader: resp.Body, emit: func(bytes int64) {
+			// The completed count is reported once io.Copy is done.
+			if bytes != resp.ContentLength {
+				progress(bytes, resp.ContentLength)
+			}
+		}}
+		written, err := io.Copy(file, body)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write file: %v", err)
+		}
+		progress(written, written)
+
+		hash, err := fileSHA256(targetPath)
+		if err != nil {
+			return fmt.Errorf("failed to hash download: %v", err)
+		}
+		if checksum != "" && hash != checksum {
+			os.Remove(targetPath)
+			return kindErrorf(ErrChecksumMismatch, "checksum mismatch for %s: expected %s, got %s", redactURL(req.URL), checksum, hash)
+		}
+		err = pm.storeInCache(url, hash, targetPath)
+		if err != nil {
+			fmt.Fprintf(pm.progress, "Warning: failed to cache download: %v\n", err)
+		}
+	}
+
+	err = os.Chmod(targetPath, mode)
+	if err != nil {
+		return fmt.Errorf("failed to set permissions: %v", err)
+	}
+
+	return nil
+}
+func (pm *Manager) restoreFromVendor(url, targetPath string) bool {
+	for _, entry := range pm.vendor {
+		if entry.URL != url {
+			continue
+		}
+		vendoredPath := pm.targetPath(filepath.FromSlash(entry.File))
+		hash, err := fileSHA256(vendoredPath)
+		if err != nil || hash != entry.SHA256 {
+			fmt.Fprintf(pm.progress, "Warning: vendored %s is missing or does not match its checksum, ignoring it\n", entry.File)
+			return false
+		}
+		return copyFile(vendoredPath, targetPath) == nil
+	}
+	return false
+}
+func (pm *Manager) cacheEntryPath(url, checksum string) string {
+	// A URL can serve different content over time (a re-uploaded asset,
+	// a moved tag), so entries are keyed by what they contain as well.
+	sum := sha256.Sum256([]byte(url + "\n" + checksum))
+	return filepath.Join(pm.cacheDir, hex.EncodeToString(sum[:]))
+}
+func (pm *Manager) restoreFromCache(url, checksum, targetPath string) bool {
+	// Without a checksum from the lock or the release there is nothing to
+	// check an entry against, so the file is downloaded.
+	if pm.cacheDir == "" || checksum == "" {
+		return false
+	}
+	entryPath := pm.cacheEntryPath(url, checksum)
+
+	actualHash, err := fileSHA256(entryPath)
+	if err != nil {
+		return false
+	}
+	if actualHash != checksum {
+		fmt.Fprintf(pm.progress, "Warning: cache entry for %s is corrupted, downloading again\n", url)
+		os.Remove(entryPath)
+		return false
+	}
+
+	return copyFile(entryPath, targetPath) == nil
+}
+func (pm *Manager) storeInCache(url, checksum, sourcePath string) error {
+	if pm.cacheDir == "" {
+		return nil
+	}
+	err := os.MkdirAll(pm.cacheDir, 0755)
+	if err != nil {
+		return err
+	}
+	entryPath := pm.cacheEntryPath(url, checksum)
+
+	tmpPath := entryPath + ".tmp"
+	err = copyFile(sourcePath, tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	err = os.Rename(tmpPath, entryPath)
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+func (pm *Manager) hashCachePath() string {
+	return filepath.Join(pm.cacheDir, "file-hashes.json")
+}
+func (pm *Manager) loadHashCache() hashCache {
+	// A missing or damaged cache only costs a re-hash, so it never fails
+	// the command that reads it.
+	hashes := make(hashCache)
+	if pm.cacheDir == "" {
+		return hashes
+	}
+	data, err := os.ReadFile(pm.hashCachePath())
+	if err != nil {
+		return hashes
+	}
+	if json.Unmarshal(data, &hashes) != nil {
+		return make(hashCache)
+	}
+	return hashes
+}
+func (pm *Manager) saveHashCache(hashes hashCache) error {
+	if pm.cacheDir == "" {
+		return nil
+	}
+	err := os.MkdirAll(pm.cacheDir, 0755)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(pm.hashCachePath(), data, 0644)
+}
+func (h hashCache) fileSHA256(path string) (string, error) {
+	// A file whose size and modification time are unchanged is assumed to
+	// have the content it had when it was last hashed. A nil cache hashes
+	// every time.
+	if h == nil {
+		return fileSHA256(path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", err
+	}
+	entry, exists := h[absPath]
+	if exists && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+		return entry.SHA256, nil
+	}
+
+	hash, err := fileSHA256(absPath)
+	if err != nil {
+		return "", err
+	}
+	h[absPath] = hashCacheEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), SHA256: hash}
+	return hash, nil
+}
+func (pm *Manager) copyLocalFile(sourcePath, targetPath string, mode os.FileMode) error {
+	fmt.Fprintf(pm.progress, "Copying %s...\n", sourcePath)
+	err := os.MkdirAll(filepath.Dir(targetPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	err = copyFile(sourcePath, targetPath)
+	if err != nil {
+		return fmt.Errorf("failed to copy file: %v", err)
+	}
+
+	return os.Chmod(targetPath, mode)
+}
+func copyFile(sourcePath, targetPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.Create(targetPath)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	_, err = io.Copy(target, source)
+	return err
+}
+func (pm *Manager) extractArchive(archivePath, targetDir string) error {
+	err := os.MkdirAll(targetDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create target directory: %v", err)
+	}
+	switch archiveFormat(archivePath) {
+	case "tar.gz":
+		return pm.extractTarGz(archivePath, targetDir)
+	case "tar.xz":
+		return pm.extractTarXz(archivePath, targetDir)
+	case "zip":
+		return pm.extractZip(archivePath, targetDir)
+	}
+
+	return fmt.Errorf("unsupported archive format: %s", archivePath)
+}
+func archiveFormat(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tar.xz") || strings.HasSuffix(name, ".txz"):
+		return "tar.xz"
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	}
+	return ""
+}
+func isArchiveFile(name string) bool {
+	return archiveFormat(name) != ""
+}
+func compressedFileExtension(name string) string {
+	if isArchiveFile(name) || strings.Contains(name, ".tar.") {
+		return ""
+	}
+	for _, ext := range []string{".gz", ".xz", ".bz2", ".zst"} {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+func (pm *Manager) decompressFile(compressedPath, targetPath string, mode os.FileMode) error {
+	fmt.Fprintf(pm.progress, "Decompressing %s to %s...\n", compressedPath, targetPath)
+	file, err := os.Open(compressedPath)
+	if err != nil {
+		return fmt.Errorf("failed to open compressed file: %v", err)
+	}
+	defer file.Close()
+
+	var reader io.Reader
+	switch compressedFileExtension(compressedPath) {
+	case ".gz":
+		gzReader, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to create gzip reader: %v", err)
+		}
+		defer gzReader.Close()
+		reader = gzReader
+	case ".xz":
+		xzReader, err := xz.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to create xz reader: %v", err)
+		}
+		reader = xzReader
+	case ".bz2":
+		reader = bzip2.NewReader(file)
+	case ".zst":
+		zstdReader, err := zstd.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to create zstd reader: %v", err)
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	default:
+		return fmt.Errorf("unsupported compression format: %s", compressedPath)
+	}
+
+	err = os.MkdirAll(filepath.Dir(targetPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	targetFile, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %v", targetPath, err)
+	}
+	defer targetFile.Close()
+
+	_, err = io.Copy(targetFile, reader)
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %v", targetPath, err)
+	}
+
+	return targetFile.Chmod(mode)
+}
+func (pm *Manager) extractTarGz(archivePath, targetDir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	gzReader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to create gzip reader: %v", err)
+	}
+	defer gzReader.Close()
+
+	tarReader := tar.NewReader(gzReader)
+
+	return pm.extractTarReader(tarReader, targetDir)
+}
+func (pm *Manager) extractTarXz(archivePath, targetDir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	xzReader, err := xz.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to create xz reader: %v", err)
+	}
+
+	tarReader := tar.NewReader(xzReader)
+
+	return pm.extractTarReader(tarReader, targetDir)
+}
+func (pm *Manager) extractTarReader(tarReader *tar.Reader, targetDir string) error {
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %v", err)
+		}
+
+		targetPath := filepath.Join(targetDir, header.Name)
+		if !isWithinDir(targetPath, targetDir) {
+			return fmt.Errorf("invalid file path: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(targetPath, header.FileInfo().Mode().Perm())
+			if err != nil {
+				return fmt.Errorf("failed to create directory %s: %v", targetPath, err)
+			}
+		case tar.TypeReg:
+			err = os.MkdirAll(filepath.Dir(targetPath), 0755)
+			if err != nil {
+				return fmt.Errorf("failed to create parent directory for %s: %v", targetPath, err)
+			}
+
+			mode := header.FileInfo().Mode().Perm()
+			file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return fmt.Errorf("failed to create file %s: %v", targetPath, err)
+			}
+
+			_, err = io.Copy(file, tarReader)
+			if err == nil {
+				// OpenFile's mode is filtered by the umask, so set it explicitly.
+				err = file.Chmod(mode)
+			}
+			file.Close()
+			if err != nil {
+				return fmt.Errorf("failed to write file %s: %v", targetPath, err)
+			}
+		}
+	}
+
+	return nil
+}
+func isWithinDir(path, dir string) bool {
+	dir = filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+func (pm *Manager) extractZip(archivePath, targetDir string) error {
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open ZIP archive: %v", err)
+	}
+	defer zipReader.Close()
+
+	for _, file := range zipReader.File {
+		targetPath := filepath.Join(targetDir, file.Name)
+		if !isWithinDir(targetPath, targetDir) {
+			return fmt.Errorf("invalid file path: %s", file.Name)
+		}
+
+		if file.FileInfo().IsDir() {
+			err = os.MkdirAll(targetPath, file.Mode().Perm())
+			if err != nil {
+				return fmt.Errorf("failed to create directory %s: %v", targetPath, err)
+			}
+			continue
+		}
+
+		err = os.MkdirAll(filepath.Dir(targetPath), 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create parent directory for %s: %v", targetPath, err)
+		}
+
+		err = extractZipFile(file, targetPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+func extractZipFile(file *zip.File, targetPath string) error {
+	fileReader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %v", file.Name, err)
+	}
+	defer fileReader.Close()
+
+	mode := file.Mode().Perm()
+	targetFile, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %v", targetPath, err)
+	}
+	defer targetFile.Close()
+
+	_, err = io.Copy(targetFile, fileReader)
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %v", targetPath, err)
+	}
+
+	err = targetFile.Chmod(mode)
+	if err != nil {
+		return fmt.Errorf("failed to set permissions for %s: %v", targetPath, err)
+	}
+
+	return nil
+}
+func (pm *Manager) buildAuthenticatedGitURL(source string, isPrivate bool) string {
+	if !isPrivate {
+		return source
+	}
+	gitURL, err := url.Parse(source)
+	if err != nil || gitURL.Scheme != "https" || gitURL.User != nil {
+		return source
+	}
+	token, _ := pm.credentialFor(gitURL.Hostname())
+	if token == "" {
+		return source
+	}
+	if canonicalHost(gitURL.Hostname()) == "gitlab.com" {
+		gitURL.User = url.UserPassword("oauth2", token)
+	} else {
+		gitURL.User = url.User(token)
+	}
+	return gitURL.String()
+}
+func (pm *Manager) credentialFor(host string) (string, string) {
+	// A host-specific FRACTURE_TOKEN_<HOST> wins over the provider-wide PAT,
+	// so one config can mix hosts that need different credentials.
+	host = canonicalHost(host)
+	hostEnv := "FRACTURE_TOKEN_" + strings.ToUpper(nonAlphanumeric.ReplaceAllString(host, "_"))
+	if token := pm.tokens[hostEnv]; token != "" {
+		return token, hostEnv
+	}
+	switch host {
+	case "github.com":
+		return pm.githubToken, pm.githubEnv
+	case "gitlab.com":
+		return pm.tokens["FRACTURE_GITLAB_PAT"], "FRACTURE_GITLAB_PAT"
+	}
+	return "", hostEnv
+}
+func canonicalHost(host string) string {
+	host = strings.ToLower(host)
+	switch host {
+	case "api.github.com", "codeload.github.com", "uploads.github.com", "gist.github.com", "gist.githubusercontent.com", "raw.githubusercontent.com":
+		return "github.com"
+	}
+	return host
+}
+func sourceHost(source string) string {
+	if parsed, err := url.Parse(source); err == nil && parsed.Host != "" {
+		return parsed.Hostname()
+	}
+	// scp-like git sources: git@host:owner/repo.git
+	if _, rest, found := strings.Cut(source, "@"); found {
+		host, _, _ := strings.Cut(rest, ":")
+		return host
+	}
+	return ""
+}
+func (pm *Manager) gitDependencies(deps DepsFile) []string {
+	// Repositories are cloned and refs are resolved with git ls-remote.
+	var names []string
+	for _, name := range sortedDependencyNames(deps) {
+		dep := deps[name]
+		if pm.dependencyType(name, dep) == "repository" || dep.Ref != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+func (pm *Manager) requireGit(deps DepsFile) error {
+	needed := pm.gitDependencies(deps)
+	if len(needed) == 0 {
+		return nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git is required for repository dependencies and refs (%s); install it or remove those dependencies", strings.Join(needed, ", "))
+	}
+	return nil
+}
+func (pm *Manager) getLatestCommitHash(ctx context.Context, source string, isPrivate bool) (string, error) {
+	if pm.offline {
+		return "", fmt.Errorf("cannot query latest commit for %s in offline mode", source)
+	}
+	if err := pm.checkInsecureURL(source); err != nil {
+		return "", err
+	}
+	gitURL := pm.buildAuthenticatedGitURL(source, isPrivate)
+
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", gitURL, "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		if token, envName := pm.credentialFor(sourceHost(source)); isPrivate && token == "" {
+			return "", kindErrorf(ErrPrivateNoToken, "private repository requires %s", envName)
+		}
+		return "", fmt.Errorf("failed to get latest commit for %s: %v", source, err)
+	}
+
+	lines := strings.Split(string(output), "\n")
+	if len(lines) > 0 && len(lines[0]) > 0 {
+		parts := strings.Fields(lines[0])
+		if len(parts) > 0 && isFullCommitHash(parts[0]) {
+			return parts[0], nil
+		}
+	}
+	return "", fmt.Errorf("failed to parse git ls-remote output")
+}
+func (pm *Manager) cloneOrUpdateRepo(ctx context.Context, source, targetPath, commit string, isPrivate bool, sparse []string) error {
+	if pm.offline {
+		return fmt.Errorf("cannot clone or pull %s in offline mode", source)
+	}
+	if err := pm.checkInsecureURL(source); err != nil {
+		return err
+	}
+	gitURL := pm.buildAuthenticatedGitURL(source, isPrivate)
+
+	if _, err := os.Stat(targetPath); os.IsNotExist(err) && len(sparse) > 0 {
+		// Blobs outside the sparse paths are never downloaded.
+		fmt.Fprintf(pm.progress, "Cloning %s to %s (sparse: %s)...\n", source, targetPath, strings.Join(sparse, ", "))
+		err := exec.CommandContext(ctx, "git", "clone", "--filter=blob:none", "--no-checkout", gitURL, targetPath).Run()
+		if err != nil {
+			return err
+		}
+		err = setSparsePaths(ctx, targetPath, sparse)
+		if err != nil {
+			return err
+		}
+		if commit != "" {
+			return checkoutCommit(ctx, targetPath, commit)
+		}
+		return exec.CommandContext(ctx, "git", "-C", targetPath, "checkout").Run()
+	} else if os.IsNotExist(err) {
+		fmt.Fprintf(pm.progress, "Cloning %s to %s...\n", source, targetPath)
+		err := exec.CommandContext(ctx, "git", "clone", gitURL, targetPath).Run()
+		if err != nil || commit == "" {
+			return err
+		}
+		return checkoutCommit(ctx, targetPath, commit)
+	} else {
+		fmt.Fprintf(pm.progress, "Updating %s...\n", targetPath)
+		err := setSparsePaths(ctx, targetPath, sparse)
+		if err != nil {
+			return err
+		}
+		if commit != "" {
+			return checkoutCommit(ctx, targetPath, commit)
+		}
+		cmd := exec.CommandContext(ctx, "git", "-C", targetPath, "pull", "origin", "main")
+		err = cmd.Run()
+		if err != nil {
+			cmd = exec.CommandContext(ctx, "git", "-C", targetPath, "pull", "origin", "master")
+			return cmd.Run()
+		}
+		return err
+	}
+}
+func checkoutCommit(ctx context.Context, repoPath, commit string) error {
+	// Fetching the resolved commit itself works whatever the default branch
+	// is called and however far the branch has moved since ls-remote.
+	output, err := exec.CommandContext(ctx, "git", "-C", repoPath, "fetch", "origin", commit).CombinedOutput()
+	if err != nil {
+		// Some servers refuse fetches by SHA; a fresh clone already has it.
+		verify := exec.CommandContext(ctx, "git", "-C", repoPath, "rev-parse", "--verify", "--quiet", commit+"^{commit}")
+		if verify.Run() != nil {
+			return fmt.Errorf("git fetch of %s failed: %v: %s", ShortHash(commit), err, strings.TrimSpace(string(output)))
+		}
+	}
+	output, err = exec.CommandContext(ctx, "git", "-C", repoPath, "checkout", "--detach", commit).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git checkout of %s failed: %v: %s", ShortHash(commit), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+func setSparsePaths(ctx context.Context, repoPath string, sparse []string) error {
+	if len(sparse) == 0 {
+		// sparse was removed from the config: bring back the full tree.
+		if _, err := os.Stat(filepath.Join(repoPath, ".git", "info", "sparse-checkout")); err != nil {
+			return nil
+		}
+		return exec.CommandContext(ctx, "git", "-C", repoPath, "sparse-checkout", "disable").Run()
+	}
+	// Non-cone patterns, anchored at the root, so single files work as well
+	// as directories.
+	args := []string{"-C", repoPath, "sparse-checkout", "set", "--no-cone"}
+	for _, sparsePath := range sparse {
+		args = append(args, "/"+strings.Trim(filepath.ToSlash(sparsePath), "/"))
+	}
+	output, err := exec.CommandContext(ctx, "git", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git sparse-checkout failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+func (pm *Manager) determineDependencyType(name string) string {
+	if strings.Contains(strings.ToLower(name), "provider") {
+		return "binary"
+	}
+	if strings.Contains(strings.ToLower(name), "source") {
+		return "source"
+	}
+	return "repository"
+}
+func (pm *Manager) installedVersionSatisfies(ctx context.Context, depName string, dep Dependency) (bool, string) {
+	check := dep.SatisfiedIfVersion
+	if check == nil {
+		return false, ""
+	}
+	installed, err := pm.installedVersion(ctx, check)
+	if err != nil {
+		fmt.Fprintf(pm.progress, "Version check for %s: %v, installing\n", depName, err)
+		return false, ""
+	}
+	satisfied, err := satisfiesConstraint(installed, check.Constraint)
+	if err != nil {
+		fmt.Fprintf(pm.progress, "Warning: %s: %v, installing\n", depName, err)
+		return false, installed
+	}
+	if !satisfied {
+		fmt.Fprintf(pm.progress, "Installed version %s of %s does not satisfy %s\n", installed, depName, check.Constraint)
+	}
+	return satisfied, installed
+}
+func (pm *Manager) installedVersion(ctx context.Context, check *VersionCheck) (string, error) {
+	if len(check.Command) == 0 {
+		return "", fmt.Errorf("satisfied_if_version.command is empty")
+	}
+	command := make([]string, len(check.Command))
+	for i, arg := range check.Command {
+		command[i] = expandEnvVars(arg)
+	}
+
+	output, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v", strings.Join(command, " "), err)
+	}
+
+	expression := check.Regex
+	if expression == "" {
+		expression = `v?(\d+(?:\.\d+){1,2}(?:-[0-9A-Za-z.-]+)?)`
+	}
+	pattern, err := regexp.Compile(expression)
+	if err != nil {
+		return "", fmt.Errorf("invalid satisfied_if_version.regex: %v", err)
+	}
+	match := pattern.FindStringSubmatch(string(output))
+	if match == nil {
+		return "", fmt.Errorf("no version found in output of %s", strings.Join(command, " "))
+	}
+	if len(match) > 1 {
+		return match[1], nil
+	}
+	return match[0], nil
+}
+func (pm *Manager) skipUnsupported(name string, dep Dependency) bool {
+	// Every command skips dependencies limited to other platforms; the
+	// caller decides what happens to their lock entry.
+	if pm.isPlatformSupported(dep) {
+		return false
+	}
+	fmt.Fprintf(pm.progress, "⏭️  Skipping %s: not supported on %s/%s (platforms: %v)\n", name, runtime.GOOS, runtime.GOARCH, dep.Platforms)
+	return true
+}
+func (pm *Manager) isPlatformSupported(dep Dependency) bool {
+	if len(dep.Platforms) == 0 {
+		return true
+	}
+	current := runtime.GOOS + "/" + runtime.GOARCH
+	for _, platform := range dep.Platforms {
+		if platform == current || platform == runtime.GOOS {
+			return true
+		}
+	}
+	return false
+}
+func (pm *Manager) dependencyContext(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	// Downloads only see the request, so they find the dependency they
+	// report progress for here.
+	ctx = context.WithValue(ctx, dependencyKey{}, name)
+	if pm.timeout > 0 {
+		return context.WithTimeout(ctx, pm.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+func (f EventHandlerFunc) HandleEvent(event Event) {
+	f(event)
+}
+func (pm *Manager) emit(event Event) {
+	if pm.events != nil {
+		pm.events.HandleEvent(event)
+	}
+}
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.bytes += int64(n)
+	// Reads come in small chunks, so only report a few times a second.
+	if now := time.Now(); now.Sub(r.last) >= 100*time.Millisecond {
+		r.last = now
+		r.emit(r.bytes)
+	}
+	return n, err
+}
+func (pm *Manager) contextError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s: %w", pm.timeout, err)
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return fmt.Errorf("interrupted: %w", err)
+	}
+	return err
+}
+func kindErrorf(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+func (e *kindError) Error() string {
+	return e.msg
+}
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+func statusError(resp *http.Response, message string) error {
+	// GitHub answers an exhausted rate limit with 403 and a zero
+	// X-RateLimit-Remaining; other hosts use 429.
+	switch {
+	case resp.StatusCode == 404:
+		return kindErrorf(ErrNotFound, "%s", message)
+	case resp.StatusCode == 429 || (resp.StatusCode == 403 && resp.Header.Get("X-RateLimit-Remaining") == "0"):
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return kindErrorf(ErrRateLimited, "%s: rate limit exceeded, resets at %s", message, time.Unix(reset, 0).Format("15:04:05"))
+		}
+		return kindErrorf(ErrRateLimited, "%s: rate limit exceeded", message)
+	}
+	return errors.New(message)
+}
+func (pm *Manager) installDependency(ctx context.Context, depName string, dep Dependency) (LockDependency, error) {
+	ctx, cancel := pm.dependencyContext(ctx, depName)
+	defer cancel()
+
+	resolved, err := pm.resolveDependency(ctx, depName, dep)
+	if err != nil {
+		return LockDependency{}, pm.contextError(ctx, err)
+	}
+
+	if !pm.confirmUpdate(resolved) {
+		return LockDependency{}, errUpdateDeclined
+	}
+	if locked, exists := pm.installLock[depName]; exists {
+		resolved.useLockedChecksums(locked)
+	}
+
+	err = pm.fetchDependency(ctx, resolved)
+	if err != nil {
+		return LockDependency{}, pm.contextError(ctx, err)
+	}
+
+	if dep.KeepVersions > 0 {
+		err = pm.pruneOldVersions(resolved)
+		if err != nil {
+			fmt.Fprintf(pm.progress, "Warning: failed to prune old versions of %s: %v\n", depName, err)
+		}
+	}
+
+	lockDep := resolved.lockDependency()
+	lockDep.Installed = pm.installedChecksums(resolved)
+	pm.reportInstalled(resolved, lockDep)
+	pm.emit(Event{Kind: EventInstalled, Dependency: depName, Version: resolved.Version, Path: resolved.ExpandedPath})
+	return lockDep, nil
+}
+func (pm *Manager) pruneOldVersions(resolved *ResolvedDependency) error {
+	dep := resolved.Dependency
+	segments := strings.Split(filepath.ToSlash(dep.Path), "/")
+	versionIndex := -1
+	for i, segment := range segments {
+		if strings.Contains(segment, "@VERSION") {
+			versionIndex = i
+			break
+		}
+	}
+	if versionIndex < 0 {
+		return fmt.Errorf("keep_versions requires @VERSION in path")
+	}
+
+	// Only the directory (or file) named after the version is pruned, even
+	// when @VERSION appears higher up in a longer path.
+	parent := pm.targetPath(filepath.FromSlash(strings.Join(segments[:versionIndex], "/")))
+	template := segments[versionIndex]
+	literal := template
+	for _, variable := range []string{"@VERSION", "@TIMESTAMP", "@ASSET_EXTENSION"} {
+		literal = strings.ReplaceAll(literal, variable, "")
+	}
+	if literal == "" {
+		return fmt.Errorf("keep_versions needs fixed text around @VERSION in %q to tell versions apart from other files", template)
+	}
+	expression := regexp.QuoteMeta(template)
+	// Versions always contain a digit, which keeps unrelated neighbours such
+	// as "mytool-config" out of the match.
+	expression = strings.ReplaceAll(expression, "@VERSION", "(.*[0-9].*)")
+	expression = strings.ReplaceAll(expression, "@TIMESTAMP", "[0-9]+")
+	expression = strings.ReplaceAll(expression, "@ASSET_EXTENSION", ".*")
+	pattern, err := regexp.Compile("^" + expression + "$")
+	if err != nil {
+		return err
+	}
+
+	expandedSegments := strings.Split(filepath.ToSlash(resolved.ExpandedPath), "/")
+	if len(expandedSegments) != len(segments) {
+		return fmt.Errorf("cannot match %s against %s", resolved.ExpandedPath, dep.Path)
+	}
+	current := expandedSegments[versionIndex]
+	currentInfo, err := os.Stat(filepath.Join(parent, current))
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return err
+	}
+	type install struct {
+		name    string
+		modTime time.Time
+	}
+	var installs []install
+	for _, entry := range entries {
+		if entry.Name() == current || entry.IsDir() != currentInfo.IsDir() || !pattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		installs = append(installs, install{name: entry.Name(), modTime: info.ModTime()})
+	}
+	sort.Slice(installs, func(i, j int) bool {
+		return installs[i].modTime.After(installs[j].modTime)
+	})
+
+	// The version just installed counts towards keep_versions.
+	for i, old := range installs {
+		if i < dep.KeepVersions-1 {
+			continue
+		}
+		oldPath := filepath.Join(parent, old.name)
+		fmt.Fprintf(pm.progress, "🧹 Removing old version of %s: %s\n", resolved.Name, oldPath)
+		err = os.RemoveAll(oldPath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (t *decisionTrace) record(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.steps = append(t.steps, fmt.Sprintf(format, args...))
+}
+func assetNames(assets []GitHubAsset) []string {
+	names := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		names = append(names, asset.Name)
+	}
+	return names
+}
+func (pm *Manager) dependencyType(depName string, dep Dependency) string {
+	if dep.Type != "" {
+		return dep.Type
+	}
+	return pm.determineDependencyType(depName)
+}
+func (pm *Manager) resolveDependency(ctx context.Context, depName string, dep Dependency) (*ResolvedDependency, error) {
+	pm.emit(Event{Kind: EventResolveStarted, Dependency: depName, Version: dep.Version})
+	depType := dep.Type
+	if depType == "" {
+		depType = pm.determineDependencyType(depName)
+		pm.trace.record("type: %s (inferred from the dependency name, no \"type\" set)", depType)
+	} else {
+		pm.trace.record("type: %s (set in config)", depType)
+	}
+
+	if dep.Bin && dep.Path == "" {
+		if depType != "binary" {
+			return nil, fmt.Errorf("bin is only supported for binary dependencies")
+		}
+		binDir, err := defaultBinDir()
+		if err != nil {
+			return nil, err
+		}
+		dep.Path = binDir
+		pm.trace.record("path: %s (default bin directory, bin=true)", binDir)
+		warnIfNotOnPath(pm.progress, binDir)
+	}
+
+	fileMode, err := fileModeFor(dep, depType)
+	if err != nil {
+		return nil, err
+	}
+
+	if dep.Versions > 0 {
+		return nil, fmt.Errorf("versions dependencies are only resolved by install, update, lock, diff, vendor and export")
+	}
+
+	localPath, isLocal := localSourcePath(dep.Source)
+	if dep.Version != "" {
+		if isLocal || (depType != "binary" && depType != "source") {
+			return nil, fmt.Errorf("version can only pin binary and source dependencies from GitHub releases")
+		}
+		if dep.Ref != "" {
+			return nil, fmt.Errorf("version and ref cannot be combined, ref already selects what is downloaded")
+		}
+	}
+
+	if dep.Prerelease && (isLocal || (depType != "binary" && depType != "source") || dep.Ref != "") {
+		return nil, fmt.Errorf("prerelease only applies to binary and source dependencies resolved from GitHub releases")
+	}
+	if dep.Resolve != "" && dep.Resolve != "semver" {
+		return nil, fmt.Errorf("unknown resolve %q, expected \"semver\"", dep.Resolve)
+	}
+	if dep.Resolve != "" && (isLocal || (depType != "binary" && depType != "source") || dep.Ref != "") {
+		return nil, fmt.Errorf("resolve only applies to binary and source dependencies resolved from GitHub releases")
+	}
+
+	if dep.Flatten {
+		if depType != "binary" && depType != "file" {
+			return nil, fmt.Errorf("flatten is only supported for binary and file dependencies, source archives are always flattened")
+		}
+		if !dep.Extract {
+			return nil, fmt.Errorf("flatten requires extract to be enabled")
+		}
+	}
+
+	if len(dep.Sparse) > 0 {
+		if depType != "repository" {
+			return nil, fmt.Errorf("sparse is only supported for repository dependencies")
+		}
+		for _, sparsePath := range dep.Sparse {
+			cleaned := path.Clean(filepath.ToSlash(sparsePath))
+			if sparsePath == "" || path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+				return nil, fmt.Errorf("sparse path %q must be a path inside the repository", sparsePath)
+			}
+		}
+	}
+
+	if dep.InstallPackage {
+		if depType != "binary" && depType != "file" {
+			return nil, fmt.Errorf("install_package is only supported for binary and file dependencies")
+		}
+		if dep.Extract {
+			return nil, fmt.Errorf("install_package cannot be combined with extract, the package is installed as downloaded")
+		}
+		if isAssetGlob(dep.AssetName) {
+			return nil, fmt.Errorf("install_package cannot be used with a glob asset_name, select exactly one .deb or .rpm asset")
+		}
+	}
+
+	var resolved *ResolvedDependency
+	switch {
+	case isLocal && depType != "repository":
+		resolved, err = pm.resolveLocalDependency(depName, dep, depType, localPath)
+	case depType == "source":
+		resolved, err = pm.resolveSourceDependency(ctx, depName, dep)
+	case depType == "binary":
+		resolved, err = pm.resolveBinaryDependency(ctx, depName, dep)
+	case depType == "file":
+		resolved, err = pm.resolveFileDependency(ctx, depName, dep)
+	default:
+		resolved, err = pm.resolveRepositoryDependency(ctx, depName, dep)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resolved.FileMode = fileMode
+	return resolved, nil
+}
+func downloadMode(resolved *ResolvedDependency, extracting bool) os.FileMode {
+	// Archives are only read before extraction and never need the exec bit.
+	if extracting {
+		return 0644
+	}
+	return resolved.FileMode
+}
+func fileModeFor(dep Dependency, depType string) (os.FileMode, error) {
+	if dep.Mode != "" && dep.Executable != nil {
+		return 0, fmt.Errorf("mode and executable cannot be used together")
+	}
+	if dep.Executable != nil {
+		if *dep.Executable {
+			return 0755, nil
+		}
+		return 0644, nil
+	}
+	if dep.Mode != "" {
+		mode, err := strconv.ParseUint(dep.Mode, 8, 32)
+		if err != nil || mode > 0777 {
+			return 0, fmt.Errorf("invalid mode %q, expected an octal permission like \"0644\"", dep.Mode)
+		}
+		return os.FileMode(mode), nil
+	}
+	if depType == "binary" {
+		return 0755, nil
+	}
+	return 0644, nil
+}
+func defaultBinDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		localAppData := os.Getenv("LOCALAPPDATA")
+		if localAppData == "" {
+			return "", fmt.Errorf("LOCALAPPDATA is not set, cannot determine default bin directory")
+		}
+		return filepath.Join(localAppData, "fracture", "bin"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %v", err)
+	}
+	return filepath.Join(home, ".local", "bin"), nil
+}
+func warnIfNotOnPath(w io.Writer, dir string) {
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(entry) == filepath.Clean(dir) {
+			return
+		}
+	}
+	fmt.Fprintf(w, "⚠️  Warning: %s is not on your PATH. Add it to run installed tools directly.\n", dir)
+}
+func (pm *Manager) targetPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(pm.workDir, path)
+}
+func (pm *Manager) validateSourceDependency(dep Dependency) error {
+	if dep.AssetName != "" {
+		return fmt.Errorf("asset_name is not allowed for source type dependencies")
+	}
+	if dep.AssetSuffix != "" {
+		return fmt.Errorf("asset_suffix is not allowed for source type dependencies")
+	}
+	if dep.AssetOS != "" || dep.AssetArch != "" {
+		return fmt.Errorf("asset_os and asset_arch are not allowed for source type dependencies")
+	}
+	if dep.Extract && dep.Filename != "" {
+		return fmt.Errorf("filename cannot be used with extract=true for source type dependencies")
+	}
+	if dep.AssetExtension == "tar.xz" || dep.AssetExtension == "txz" {
+		return fmt.Errorf("GitHub only serves source archives as zip or tar.gz, asset_extension '%s' is not available", dep.AssetExtension)
+	}
+	if dep.AssetExtension != "" && dep.AssetExtension != "zip" && dep.AssetExtension != "tar.gz" && dep.AssetExtension != "tgz" {
+		return fmt.Errorf("asset_extension for source type must be 'zip', 'tar.gz' or 'tgz', got '%s'", dep.AssetExtension)
+	}
+	if dep.Extract && (strings.Contains(dep.Path, "@ASSET_EXTENSION") || strings.Contains(dep.Filename, "@ASSET_EXTENSION")) {
+		return fmt.Errorf("@ASSET_EXTENSION placeholder cannot be used with extract=true")
+	}
+	return nil
+}
+func (pm *Manager) resolveSourceDependency(ctx context.Context, depName string, dep Dependency) (*ResolvedDependency, error) {
+	err := pm.validateSourceDependency(dep)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, repo, err := pm.extractRepoInfo(dep.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repository URL: %v", err)
+	}
+
+	var version, archiveRef, commit, releaseNotes, publishedAt string
+	if dep.Ref != "" {
+		kind, refCommit, err := pm.resolveGitRef(ctx, dep.Source, dep.Ref, dep.Private)
+		if err != nil {
+			return nil, err
+		}
+		version = dep.Ref
+		commit = refCommit
+		if kind == "tag" {
+			archiveRef = "refs/tags/" + dep.Ref
+		} else {
+			// Branches move, so the archive is pinned to the commit the
+			// branch pointed at when it was resolved.
+			archiveRef = refCommit
+		}
+		pm.trace.record("ref: %s (%s at commit %s)", dep.Ref, kind, refCommit)
+	} else {
+		release, err := pm.resolveRelease(ctx, owner, repo, dep)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get release info: %w", err)
+		}
+		version = release.TagName
+		archiveRef = "refs/tags/" + release.TagName
+		releaseNotes = release.Body
+		publishedAt = release.PublishedAt
+	}
+
+	sourceFormat := sourceFormatFor(dep)
+	if dep.AssetExtension != "" {
+		pm.trace.record("format: %s (asset_extension)", sourceFormat)
+	} else {
+		pm.trace.record("format: %s (default)", sourceFormat)
+	}
+
+	expandedPath := pm.expandPathWithOptions(dep.Path, strings.TrimPrefix(version, dep.TagPrefix), sourceFormat, dep.Extract)
+	fmt.Fprintf(pm.progress, "Original path: %s\n", dep.Path)
+	fmt.Fprintf(pm.progress, "Expanded path: %s\n", expandedPath)
+
+	var downloadURL string
+	switch {
+	case dep.Private:
+		// github.com/.../archive URLs don't reliably honour token auth for
+		// private repositories, the API tarball/zipball endpoints do.
+		endpoint := "tarball"
+		if sourceFormat == "zip" {
+			endpoint = "zipball"
+		}
+		downloadURL = fmt.Sprintf("https://api.github.com/repos/%s/%s/%s/%s", owner, repo, endpoint, strings.TrimPrefix(archiveRef, "refs/tags/"))
+	case sourceFormat == "zip":
+		downloadURL = fmt.Sprintf("https://github.com/%s/%s/archive/%s.zip", owner, repo, archiveRef)
+	default:
+		downloadURL = fmt.Sprintf("https://github.com/%s/%s/archive/%s.tar.gz", owner, repo, archiveRef)
+	}
+	pm.trace.record("path: %s -> %s", dep.Path, expandedPath)
+	pm.trace.record("download: %s", downloadURL)
+
+	return &ResolvedDependency{
+		Name:         depName,
+		Type:         "source",
+		Dependency:   dep,
+		Owner:        owner,
+		Repo:         repo,
+		Version:      version,
+		ExpandedPath: expandedPath,
+		SourceFormat: sourceFormat,
+		DownloadURL:  downloadURL,
+		ReleaseNotes: releaseNotes,
+		Ref:          dep.Ref,
+		Commit:       commit,
+		PublishedAt:  publishedAt,
+	}, nil
+}
+func (pm *Manager) resolveGitRef(ctx context.Context, source, ref string, isPrivate bool) (string, string, error) {
+	if pm.offline {
+		return "", "", fmt.Errorf("cannot resolve ref %s of %s in offline mode", ref, source)
+	}
+	if err := pm.checkInsecureURL(source); err != nil {
+		return "", "", err
+	}
+	gitURL := pm.buildAuthenticatedGitURL(source, isPrivate)
+
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", gitURL, "refs/heads/"+ref, "refs/tags/"+ref, "refs/tags/"+ref+"^{}")
+	output, err := cmd.Output()
+	if err != nil {
+		if token, envName := pm.credentialFor(sourceHost(source)); isPrivate && token == "" {
+			return "", "", kindErrorf(ErrPrivateNoToken, "private repository requires %s", envName)
+		}
+		return "", "", fmt.Errorf("failed to resolve ref %s of %s: %v", ref, source, err)
+	}
+
+	var branchCommit, tagCommit, peeledCommit string
+	for _, line := range strings.Split(string(output), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) != 2 || !isFullCommitHash(parts[0]) {
+			continue
+		}
+		switch parts[1] {
+		case "refs/heads/" + ref:
+			branchCommit = parts[0]
+		case "refs/tags/" + ref:
+			tagCommit = parts[0]
+		case "refs/tags/" + ref + "^{}":
+			peeledCommit = parts[0]
+		}
+	}
+	// Annotated tags point at a tag object; the peeled entry is the commit.
+	if peeledCommit != "" {
+		tagCommit = peeledCommit
+	}
+
+	switch {
+	case branchCommit != "" && tagCommit != "":
+		return "", "", fmt.Errorf("ref %s is both a branch and a tag in %s", ref, source)
+	case branchCommit != "":
+		return "branch", branchCommit, nil
+	case tagCommit != "":
+		return "tag", tagCommit, nil
+	}
+	return "", "", fmt.Errorf("ref %s not found in %s", ref, source)
+}
+func sourceFormatFor(dep Dependency) string {
+	if dep.AssetExtension != "" {
+		return dep.AssetExtension
+	}
+	return "tar.gz"
+}
+func (pm *Manager) resolveBinaryDependency(ctx context.Context, depName string, dep Dependency) (*ResolvedDependency, error) {
+	owner, repo, err := pm.extractRepoInfo(dep.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repository URL: %v", err)
+	}
+
+	if isAssetGlob(dep.AssetName) {
+		if _, err := path.Match(dep.AssetName, ""); err != nil {
+			return nil, fmt.Errorf("invalid asset_name pattern '%s': %v", dep.AssetName, err)
+		}
+		if dep.Filename != "" {
+			return nil, fmt.Errorf("filename cannot be used with a glob asset_name, matching assets keep their own names")
+		}
+		if dep.AssetIndex != nil {
+			return nil, fmt.Errorf("asset_index cannot be used with a glob asset_name, all matching assets are installed")
+		}
+	}
+
+	release, err := pm.resolveRelease(ctx, owner, repo, dep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release info: %w", err)
+	}
+
+	expandedPath := pm.expandPath(dep.Path, strings.TrimPrefix(release.TagName, dep.TagPrefix))
+	fmt.Fprintf(pm.progress, "Original path: %s\n", dep.Path)
+	fmt.Fprintf(pm.progress, "Expanded path: %s\n", expandedPath)
+	pm.trace.record("path: %s -> %s", dep.Path, expandedPath)
+
+	assets, err := pm.selectAssets(release, dep)
+	if err != nil {
+		return nil, err
+	}
+
+	assetURL := func(asset GitHubAsset) string {
+		if dep.Private {
+			return assetAPIURL(owner, repo, asset.ID)
+		}
+		return asset.BrowserDownloadURL
+	}
+	resolved := &ResolvedDependency{
+		Name:         depName,
+		Type:         "binary",
+		Dependency:   dep,
+		Owner:        owner,
+		Repo:         repo,
+		Version:      release.TagName,
+		ExpandedPath: expandedPath,
+		DownloadURL:  assetURL(assets[0]),
+		Asset:        &assets[0],
+		ReleaseNotes: release.Body,
+		PublishedAt:  release.PublishedAt,
+	}
+	if len(assets) > 1 {
+		for _, asset := range assets {
+			resolved.Parts = append(resolved.Parts, resolved.withAsset(asset, assetURL(asset)))
+		}
+	}
+	for _, part := range resolved.allParts() {
+		pm.trace.record("download: %s", part.DownloadURL)
+	}
+	return resolved, nil
+}
+func (r *ResolvedDependency) withAsset(asset GitHubAsset, downloadURL string) *ResolvedDependency {
+	part := *r
+	part.Asset = &asset
+	part.DownloadURL = downloadURL
+	part.Files = nil
+	part.Parts = nil
+	return &part
+}
+func (r *ResolvedDependency) allParts() []*ResolvedDependency {
+	if len(r.Parts) == 0 {
+		return []*ResolvedDependency{r}
+	}
+	return r.Parts
+}
+func isAssetGlob(assetName string) bool {
+	return strings.ContainsAny(assetName, "*?[")
+}
+func (pm *Manager) selectAssets(release *GitHubRelease, dep Dependency) ([]GitHubAsset, error) {
+	fmt.Fprintf(pm.progress, "Available assets in release %s:\n", release.TagName)
+	for i, asset := range release.Assets {
+		fmt.Fprintf(pm.progress, "  [%d] %s -> %s\n", i, asset.Name, asset.BrowserDownloadURL)
+	}
+
+	var candidateAssets []GitHubAsset
+	pm.trace.record("candidates: %d assets in release %s: %v", len(release.Assets), release.TagName, assetNames(release.Assets))
+
+	glob := isAssetGlob(dep.AssetName)
+	if dep.AssetName != "" {
+		fmt.Fprintf(pm.progress, "Filtering assets by asset_name: %s\n", dep.AssetName)
+		for _, asset := range release.Assets {
+			matched := strings.Contains(asset.Name, dep.AssetName)
+			if glob {
+				matched, _ = path.Match(dep.AssetName, asset.Name)
+			}
+			if matched {
+				candidateAssets = append(candidateAssets, asset)
+			}
+		}
+		pm.trace.record("asset_name %q: %d -> %d assets %v", dep.AssetName, len(release.Assets), len(candidateAssets), assetNames(candidateAssets))
+		if len(candidateAssets) == 0 {
+			return nil, fmt.Errorf("no assets found containing asset_name '%s' in release %s", dep.AssetName, release.TagName)
+		}
+		fmt.Fprintf(pm.progress, "Found %d assets matching asset_name '%s'\n", len(candidateAssets), dep.AssetName)
+	} else {
+		candidateAssets = release.Assets
+		pm.trace.record("asset_name: not set, all assets kept")
+	}
+
+	if dep.AssetExtension != "" {
+		fmt.Fprintf(pm.progress, "Filtering assets by asset_extension: %s\n", dep.AssetExtension)
+		var extensionFilteredAssets []GitHubAsset
+
+		extension := dep.AssetExtension
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+
+		for _, asset := range candidateAssets {
+			if strings.HasSuffix(asset.Name, extension) {
+				extensionFilteredAssets = append(extensionFilteredAssets, asset)
+			}
+		}
+
+		pm.trace.record("asset_extension %q: %d -> %d assets %v", extension, len(candidateAssets), len(extensionFilteredAssets), assetNames(extensionFilteredAssets))
+		if len(extensionFilteredAssets) == 0 {
+			return nil, fmt.Errorf("no assets found with asset_extension '%s' in release %s", dep.AssetExtension, release.TagName)
+		}
+
+		candidateAssets = extensionFilteredAssets
+		fmt.Fprintf(pm.progress, "Found %d assets matching asset_extension '%s'\n", len(candidateAssets), dep.AssetExtension)
+	}
+
+	if dep.AssetOS != "" || dep.AssetArch != "" {
+		fmt.Fprintf(pm.progress, "Filtering assets by platform: %s\n", platformLabel(dep.AssetOS, dep.AssetArch))
+		var platformAssets []GitHubAsset
+		for _, asset := range candidateAssets {
+			if assetMatchesPlatform(asset.Name, dep.AssetOS, dep.AssetArch) {
+				platformAssets = append(platformAssets, asset)
+			}
+		}
+		pm.trace.record("asset_os %q, asset_arch %q: %d -> %d assets %v", dep.AssetOS, dep.AssetArch, len(candidateAssets), len(platformAssets), assetNames(platformAssets))
+		if len(platformAssets) == 0 {
+			return nil, fmt.Errorf("no assets found for platform %s in release %s", platformLabel(dep.AssetOS, dep.AssetArch), release.TagName)
+		}
+		candidateAssets = platformAssets
+		fmt.Fprintf(pm.progress, "Found %d assets for platform %s\n", len(candidateAssets), platformLabel(dep.AssetOS, dep.AssetArch))
+	}
+	platform := dep.AssetOS != "" || dep.AssetArch != ""
+
+	assetSuffix := pm.getAssetSuffixFromDep(dep)
+	if glob && assetSuffix == "" {
+		// A glob names the assets precisely enough; every match is installed.
+		fmt.Fprintf(pm.progress, "Found %d matching assets: %v\n", len(candidateAssets), assetNames(candidateAssets))
+		pm.trace.record("selected: %v (glob asset_name installs every match)", assetNames(candidateAssets))
+		return candidateAssets, nil
+	}
+	if assetSuffix == "" && !platform {
+		var names []string
+		for _, asset := range candidateAssets {
+			names = append(names, asset.Name)
+		}
+		return nil, fmt.Errorf("asset_suffix (or asset_os/asset_arch) is required for binary dependencies. Available assets: %v", names)
+	}
+
+	var matchingAssets []GitHubAsset
+	if assetSuffix != "" {
+		for _, asset := range candidateAssets {
+			if strings.Contains(asset.Name, assetSuffix) {
+				matchingAssets = append(matchingAssets, asset)
+			}
+		}
+		pm.trace.record("asset_suffix %q: %d -> %d assets %v", assetSuffix, len(candidateAssets), len(matchingAssets), assetNames(matchingAssets))
+
+		if len(matchingAssets) == 0 {
+			return nil, fmt.Errorf("no assets found matching asset_suffix '%s' in release %s", assetSuffix, release.TagName)
+		}
+	} else {
+		matchingAssets = candidateAssets
+	}
+	if glob {
+		fmt.Fprintf(pm.progress, "Found %d matching assets: %v\n", len(matchingAssets), assetNames(matchingAssets))
+		pm.trace.record("selected: %v (glob asset_name installs every match)", assetNames(matchingAssets))
+		return matchingAssets, nil
+	}
+
+	if len(matchingAssets) > 1 && dep.AssetIndex != nil {
+		index := *dep.AssetIndex
+		if index < 0 || index >= len(matchingAssets) {
+			return nil, fmt.Errorf("asset_index %d is out of range, %d assets match the criteria", index, len(matchingAssets))
+		}
+		fmt.Fprintf(pm.progress, "Selecting asset by asset_index %d\n", index)
+		pm.trace.record("asset_index %d: picked %s out of %d matches", index, matchingAssets[index].Name, len(matchingAssets))
+		matchingAssets = matchingAssets[index : index+1]
+	}
+
+	if len(matchingAssets) > 1 && pm.selectAsset && pm.interactive {
+		index, err := promptAssetChoice(pm.out, matchingAssets)
+		if err != nil {
+			return nil, err
+		}
+		matchingAssets = matchingAssets[index : index+1]
+	}
+
+	if len(matchingAssets) > 1 {
+		var assetNames []string
+		for i, asset := range matchingAssets {
+			assetNames = append(assetNames, fmt.Sprintf("[%d] %s", i, asset.Name))
+		}
+		return nil, fmt.Errorf("multiple assets found matching criteria. Found %d assets: %v. Please refine asset_name, asset_extension, asset_suffix, asset_os or asset_arch to match exactly one asset, set asset_index, or use a glob asset_name to install them all", len(matchingAssets), assetNames)
+	}
+
+	asset := matchingAssets[0]
+	fmt.Fprintf(pm.progress, "Found matching asset: %s\n", asset.Name)
+	pm.trace.record("selected: %s", asset.Name)
+	return matchingAssets[:1], nil
+}
+func platformLabel(targetOS, targetArch string) string {
+	switch {
+	case targetOS == "":
+		return targetArch
+	case targetArch == "":
+		return targetOS
+	}
+	return targetOS + "/" + targetArch
+}
+func assetMatchesPlatform(name, targetOS, targetArch string) bool {
+	name = strings.ToLower(name)
+	if targetOS != "" && detectAssetPlatform(name, osAliases) != targetOS {
+		return false
+	}
+	if targetArch != "" {
+		arch := detectAssetPlatform(name, archAliases)
+		// macOS releases often ship one universal binary for every arch.
+		universal := arch == "universal" && targetOS == "darwin"
+		if arch != targetArch && !universal {
+			return false
+		}
+	}
+	return true
+}
+func detectAssetPlatform(name string, aliases map[string][]string) string {
+	// The longest name found between separators wins, so x86_64 is read as
+	// amd64 rather than as x86, and arm64 never as arm.
+	best, bestLength := "", 0
+	consider := func(value, alias string) {
+		longer := len(alias) > bestLength || len(alias) == bestLength && value < best
+		if longer && containsWord(name, alias) {
+			best, bestLength = value, len(alias)
+		}
+	}
+	for value, names := range aliases {
+		consider(value, value)
+		for _, alias := range names {
+			consider(value, alias)
+		}
+	}
+	return best
+}
+func containsWord(s, word string) bool {
+	isWordChar := func(c byte) bool {
+		return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+	}
+	for offset := 0; ; {
+		index := strings.Index(s[offset:], word)
+		if index < 0 {
+			return false
+		}
+		start := offset + index
+		end := start + len(word)
+		if (start == 0 || !isWordChar(s[start-1])) && (end == len(s) || !isWordChar(s[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+func (pm *Manager) confirmUpdate(resolved *ResolvedDependency) bool {
+	// Only updates of dependencies marked interactive are confirmed, and
+	// only on a terminal; CI runs proceed without asking.
+	if pm.updateLock == nil || !resolved.Dependency.Interactive || pm.assumeYes {
+		return true
+	}
+	if !pm.interactive {
+		return true
+	}
+	previous, exists := pm.updateLock[resolved.Name]
+	if !exists {
+		return true
+	}
+	current := resolved.lockDependency()
+	if sameVersion(previous.Hash, current.Hash) {
+		fmt.Fprintf(pm.out, "Reinstall %s %s over %s? [y/N]: ", resolved.Name, ShortHash(current.Version), previous.Path)
+	} else {
+		fmt.Fprintf(pm.out, "Update %s from %s to %s, overwriting %s? [y/N]: ", resolved.Name, ShortHash(previous.Version), ShortHash(current.Version), previous.Path)
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
+func promptAssetChoice(w io.Writer, assets []GitHubAsset) (int, error) {
+	fmt.Fprintf(w, "Multiple assets match the criteria:\n")
+	for i, asset := range assets {
+		fmt.Fprintf(w, "  [%d] %s\n", i, asset.Name)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprintf(w, "Select asset [0-%d]: ", len(assets)-1)
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, fmt.Errorf("failed to read selection: %v", err)
+		}
+		index, err := strconv.Atoi(strings.TrimSpace(line))
+		if err == nil && index >= 0 && index < len(assets) {
+			return index, nil
+		}
+		fmt.Fprintln(w, "Invalid selection, try again.")
+	}
+}
+func (pm *Manager) resolveRepositoryDependency(ctx context.Context, depName string, dep Dependency) (*ResolvedDependency, error) {
+	hash, err := pm.getLatestCommitHash(ctx, dep.Source, dep.Private)
+	if err != nil {
+		pm.trace.record("commit: git ls-remote failed: %v", err)
+		return nil, err
+	}
+	pm.trace.record("commit: %s (HEAD of %s via git ls-remote)", hash, dep.Source)
+
+	expandedPath := pm.expandPathWithOptions(dep.Path, ShortHash(hash), "", dep.Extract)
+	fmt.Fprintf(pm.progress, "Original path: %s\n", dep.Path)
+	fmt.Fprintf(pm.progress, "Expanded path: %s\n", expandedPath)
+	pm.trace.record("path: %s -> %s", dep.Path, expandedPath)
+
+	return &ResolvedDependency{
+		Name:         depName,
+		Type:         "repository",
+		Dependency:   dep,
+		Version:      hash,
+		ExpandedPath: expandedPath,
+	}, nil
+}
+func localSourcePath(source string) (string, bool) {
+	if strings.HasPrefix(source, "file://") {
+		return filepath.FromSlash(strings.TrimPrefix(source, "file://")), true
+	}
+	if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") || filepath.IsAbs(source) {
+		return filepath.FromSlash(source), true
+	}
+	return "", false
+}
+func (pm *Manager) resolveLocalDependency(depName string, dep Dependency, depType, localPath string) (*ResolvedDependency, error) {
+	localPath = pm.targetPath(localPath)
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("local source not found: %v", err)
+	}
+
+	var hash string
+	if info.IsDir() {
+		hash, err = dirSHA256(localPath, nil)
+	} else {
+		hash, err = fileSHA256(localPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash local source %s: %v", localPath, err)
+	}
+	pm.trace.record("local: %s (sha256 %s)", localPath, hash)
+
+	expandedPath := pm.expandPath(dep.Path, hash)
+	fmt.Fprintf(pm.progress, "Original path: %s\n", dep.Path)
+	fmt.Fprintf(pm.progress, "Expanded path: %s\n", expandedPath)
+	pm.trace.record("path: %s -> %s", dep.Path, expandedPath)
+
+	resolved := &ResolvedDependency{
+		Name:         depName,
+		Type:         depType,
+		Dependency:   dep,
+		Version:      hash,
+		ExpandedPath: expandedPath,
+		LocalPath:    localPath,
+	}
+	if !info.IsDir() {
+		resolved.Asset = &GitHubAsset{Name: filepath.Base(localPath)}
+	}
+	return resolved, nil
+}
+func (pm *Manager) resolveFileDependency(ctx context.Context, depName string, dep Dependency) (*ResolvedDependency, error) {
+	if pm.offline {
+		return nil, fmt.Errorf("cannot resolve %s in offline mode", dep.Source)
+	}
+	source := dep.Source
+	if strings.HasPrefix(source, "gist.github.com/") {
+		source = "https://" + source
+	}
+	sourceURL, err := url.Parse(source)
+	if err != nil || (sourceURL.Scheme != "https" && sourceURL.Scheme != "http") || sourceURL.Host == "" {
+		return nil, fmt.Errorf("file source must be a gist or an http(s) URL: %s", dep.Source)
+	}
+
+	var version, checksum string
+	var asset *GitHubAsset
+	if sourceURL.Hostname() == "gist.github.com" {
+		version, asset, err = pm.resolveGistFile(ctx, sourceURL, dep)
+	} else if pm.probeLock != nil {
+		version, asset, err = pm.probeRawFile(ctx, source, dep.Headers, pm.probeLock[depName])
+		checksum = version
+	} else {
+		version, asset, err = pm.resolveRawFile(ctx, source, dep.Headers)
+		checksum = version
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expandedPath := pm.expandPath(dep.Path, ShortHash(version))
+	fmt.Fprintf(pm.progress, "Original path: %s\n", dep.Path)
+	fmt.Fprintf(pm.progress, "Expanded path: %s\n", expandedPath)
+	pm.trace.record("path: %s -> %s", dep.Path, expandedPath)
+	pm.trace.record("download: %s", asset.BrowserDownloadURL)
+
+	return &ResolvedDependency{
+		Name:         depName,
+		Type:         "file",
+		Dependency:   dep,
+		Version:      version,
+		ExpandedPath: expandedPath,
+		DownloadURL:  asset.BrowserDownloadURL,
+		Asset:        asset,
+		SHA256:       checksum,
+	}, nil
+}
+func (pm *Manager) resolveGistFile(ctx context.Context, gistURL *url.URL, dep Dependency) (string, *GitHubAsset, error) {
+	gistID := strings.TrimSuffix(path.Base(gistURL.Path), ".git")
+	req, err := pm.createAuthenticatedRequest(ctx, "GET", "https://api.github.com/gists/"+gistID, mediaTypeGitHubJSON)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := pm.httpClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get gist info: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == 404 {
+		return "", nil, kindErrorf(ErrNotFound, "gist %s not found or no access", gistID)
+	}
+	if resp.StatusCode != 200 {
+		return "", nil, statusError(resp, fmt.Sprintf("GitHub API returned status %d", resp.StatusCode))
+	}
+
+	var gist GitHubGist
+	err = json.NewDecoder(resp.Body).Decode(&gist)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse GitHub API response: %v", err)
+	}
+	if len(gist.History) == 0 {
+		return "", nil, fmt.Errorf("gist %s has no revisions", gistID)
+	}
+
+	var names []string
+	for name := range gist.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fileName := dep.AssetName
+	switch {
+	case fileName != "":
+		if _, exists := gist.Files[fileName]; !exists {
+			return "", nil, fmt.Errorf("gist %s has no file %s. Available files: %s", gistID, fileName, strings.Join(names, ", "))
+		}
+	case len(names) == 1:
+		fileName = names[0]
+	default:
+		return "", nil, fmt.Errorf("gist %s has %d files, set asset_name to one of: %s", gistID, len(names), strings.Join(names, ", "))
+	}
+
+	file := gist.Files[fileName]
+	revision := gist.History[0].Version
+	pm.trace.record("gist: %s revision %s, file %s", gistID, revision, fileName)
+	// raw_url contains the revision, so the download (and cache entry) is
+	// immutable.
+	return revision, &GitHubAsset{Name: file.Filename, BrowserDownloadURL: file.RawURL, Size: file.Size}, nil
+}
+func (pm *Manager) resolveRawFile(ctx context.Context, rawURL string, headers map[string]string) (string, *GitHubAsset, error) {
+	// A raw URL has no version of its own, so the content is the version.
+	// Nothing is known about it yet, so it is always downloaded, and the
+	// fetch that follows finds it in the cache by its content hash.
+	tmpDir, err := pm.createTempDir()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create tmp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fileName := path.Base(strings.SplitN(rawURL, "?", 2)[0])
+	tmpPath := filepath.Join(tmpDir, fileName)
+	err = pm.downloadBinary(ctx, rawURL, tmpPath, false, 0644, headers)
+	if err != nil {
+		return "", nil, err
+	}
+	hash, err := fileSHA256(tmpPath)
+	if err != nil {
+		return "", nil, err
+	}
+	pm.trace.record("file: %s (sha256 %s)", rawURL, hash)
+	return hash, &GitHubAsset{Name: fileName, BrowserDownloadURL: rawURL}, nil
+}
+func (pm *Manager) probeRawFile(ctx context.Context, rawURL string, headers map[string]string, locked LockDependency) (string, *GitHubAsset, error) {
+	// Commands that resolve without installing only check that the URL
+	// answers. The content, which is the version, comes from the lock or
+	// stays unknown until the next install.
+	err := pm.checkInsecureURL(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := pm.probeURL(ctx, "HEAD", rawURL, headers)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = pm.probeURL(ctx, "GET", rawURL, headers)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to reach %s: %v", rawURL, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", nil, statusError(resp, fmt.Sprintf("server returned status %d", resp.StatusCode))
+	}
+
+	fileName := path.Base(strings.SplitN(rawURL, "?", 2)[0])
+	asset := &GitHubAsset{Name: fileName, BrowserDownloadURL: rawURL}
+	if resp.Request.Method == "HEAD" && resp.ContentLength > 0 {
+		asset.Size = resp.ContentLength
+	}
+	if locked.Type != "file" || locked.URL != rawURL {
+		pm.trace.record("file: %s (reachable, not downloaded, so its sha256 is unknown)", rawURL)
+		return "", asset, nil
+	}
+	pm.trace.record("file: %s (reachable, sha256 %s from the lock)", rawURL, locked.Version)
+	return locked.Version, asset, nil
+}
+func (pm *Manager) probeURL(ctx context.Context, method, rawURL string, headers map[string]string) (*http.Response, error) {
+	req, err := pm.newRequest(ctx, method, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	setHeaders(req, headers)
+	if method == "GET" {
+		// Servers that refuse HEAD are asked for a single byte instead.
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	return pm.httpClient.Do(req)
+}
+func dirSHA256(dir string, hashes hashCache) (string, error) {
+	// .git changes whenever git touches its index, not only when the
+	// checked-out files do, so it is left out.
+	hasher := sha256.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		fileHash, err := hashes.fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hasher, "%s  %s\n", fileHash, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+func (pm *Manager) resolveFromLock(depName string, dep Dependency, lockDep LockDependency) (*ResolvedDependency, error) {
+	if lockDep.Source != dep.Source {
+		return nil, fmt.Errorf("lock entry source %s does not match config source %s; run 'fracture lock' while online", lockDep.Source, dep.Source)
+	}
+
+	fileMode, err := fileModeFor(dep, lockDep.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := &ResolvedDependency{
+		Name:         depName,
+		Type:         lockDep.Type,
+		Dependency:   dep,
+		Version:      lockDep.Version,
+		ExpandedPath: lockDep.Path,
+		DownloadURL:  lockDep.URL,
+		FileMode:     fileMode,
+	}
+
+	if resolved.Type == "repository" {
+		return resolved, nil
+	}
+
+	if lockDep.URL == "" {
+		return nil, fmt.Errorf("lock entry has no download URL; run 'fracture lock' while online")
+	}
+	if resolved.Type == "file" {
+		resolved.Asset = &GitHubAsset{Name: lockDep.Asset, BrowserDownloadURL: lockDep.URL}
+		resolved.useLockedChecksums(lockDep)
+		return resolved, nil
+	}
+	owner, repo, err := pm.extractRepoInfo(dep.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repository URL: %v", err)
+	}
+	resolved.Owner = owner
+	resolved.Repo = repo
+
+	if resolved.Type == "source" {
+		resolved.SourceFormat = sourceFormatFor(dep)
+	} else {
+		resolved.Asset = &GitHubAsset{Name: lockDep.Asset, BrowserDownloadURL: lockDep.URL}
+		for _, asset := range lockDep.Assets {
+			resolved.Parts = append(resolved.Parts, resolved.withAsset(GitHubAsset{Name: asset.Name, BrowserDownloadURL: asset.URL}, asset.URL))
+		}
+	}
+	resolved.useLockedChecksums(lockDep)
+
+	return resolved, nil
+}
+func (pm *Manager) installFromLock(ctx context.Context, depName string, dep Dependency, lock LockFile) (LockDependency, error) {
+	if _, isLocal := localSourcePath(dep.Source); isLocal && dep.Type != "repository" {
+		return pm.installDependency(ctx, depName, dep)
+	}
+	pm.emit(Event{Kind: EventResolveStarted, Dependency: depName, Version: dep.Version})
+	ctx, cancel := pm.dependencyContext(ctx, depName)
+	defer cancel()
+
+	lockDep, exists := lock[depName]
+	if !exists {
+		return LockDependency{}, fmt.Errorf("no entry in %s; run 'fracture lock' while online", pm.lockPath)
+	}
+
+	resolved, err := pm.resolveFromLock(depName, dep, lockDep)
+	if err != nil {
+		return LockDependency{}, err
+	}
+
+	if resolved.Type == "repository" {
+		targetPath := pm.targetPath(resolved.ExpandedPath)
+		if _, err := os.Stat(targetPath); err != nil {
+			return LockDependency{}, fmt.Errorf("repository %s is not present at %s and cannot be cloned in offline mode", dep.Source, resolved.ExpandedPath)
+		}
+		lockedCommit, err := pm.expandLockedCommit(ctx, targetPath, resolved.Version)
+		if err != nil {
+			return LockDependency{}, err
+		}
+		head, err := pm.getCheckedOutCommit(ctx, targetPath)
+		if err != nil {
+			return LockDependency{}, err
+		}
+		if head != lockedCommit {
+			return LockDependency{}, fmt.Errorf("checked out commit %s does not match locked commit %s", ShortHash(head), ShortHash(lockedCommit))
+		}
+		resolved.Files = append(resolved.Files, targetPath)
+		pm.reportInstalled(resolved, lockDep)
+		pm.emit(Event{Kind: EventInstalled, Dependency: depName, Version: resolved.Version, Path: resolved.ExpandedPath})
+		return lockDep, nil
+	}
+
+	err = pm.fetchDependency(ctx, resolved)
+	if err != nil {
+		return LockDependency{}, pm.contextError(ctx, err)
+	}
+	if resolved.PackageVersion != "" {
+		lockDep.PackageVersion = resolved.PackageVersion
+	}
+	lockDep.Installed = pm.installedChecksums(resolved)
+
+	pm.reportInstalled(resolved, lockDep)
+	pm.emit(Event{Kind: EventInstalled, Dependency: depName, Version: resolved.Version, Path: resolved.ExpandedPath})
+	return lockDep, nil
+}
+func (pm *Manager) installedChecksums(resolved *ResolvedDependency) map[string]string {
+	// What verify compares against later. Repositories and extracted
+	// archives own their directory and get one hash for the whole tree;
+	// other dependencies share theirs, so their files are hashed one by one.
+	paths := resolved.Files
+	if ownsDirectory(resolved) {
+		paths = []string{pm.targetPath(resolved.ExpandedPath)}
+	}
+	checksums := make(map[string]string)
+	for _, installedPath := range paths {
+		hash, err := pathSHA256(installedPath, nil)
+		if err != nil {
+			fmt.Fprintf(pm.progress, "Warning: failed to hash %s, verify will not check %s: %v\n", installedPath, resolved.Name, err)
+			return nil
+		}
+		checksums[pm.relativePath(installedPath)] = hash
+	}
+	if len(checksums) == 0 {
+		return nil
+	}
+	return checksums
+}
+func ownsDirectory(resolved *ResolvedDependency) bool {
+	dep := resolved.Dependency
+	switch {
+	case resolved.Type == "repository":
+		return true
+	case resolved.LocalPath != "":
+		info, err := os.Stat(resolved.LocalPath)
+		return err == nil && info.IsDir()
+	case !dep.Extract || dep.Filename != "":
+		return false
+	case resolved.Type == "source":
+		return true
+	}
+	if len(resolved.Parts) > 0 {
+		for _, part := range resolved.Parts {
+			if isArchiveFile(part.Asset.Name) {
+				return true
+			}
+		}
+		return false
+	}
+	return resolved.Asset != nil && isArchiveFile(resolved.Asset.Name)
+}
+func pathSHA256(path string, hashes hashCache) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return dirSHA256(path, hashes)
+	}
+	return hashes.fileSHA256(path)
+}
+func (r *ResolvedDependency) lockDependency() LockDependency {
+	lockDep := LockDependency{
+		Name:           r.Name,
+		Path:           r.ExpandedPath,
+		Source:         r.Dependency.Source,
+		Version:        r.Version,
+		Hash:           r.Version,
+		Type:           r.Type,
+		Private:        r.Dependency.Private,
+		Extract:        r.Dependency.Extract,
+		URL:            r.DownloadURL,
+		Mirror:         r.Mirror,
+		Ref:            r.Ref,
+		Commit:         r.Commit,
+		PublishedAt:    r.PublishedAt,
+		PackageVersion: r.PackageVersion,
+		SHA256:         r.expectedSHA256(),
+	}
+	if r.Commit != "" {
+		lockDep.Hash = r.Commit
+	}
+	if r.Asset != nil {
+		lockDep.Asset = r.Asset.Name
+	}
+	for _, part := range r.Parts {
+		lockDep.Assets = append(lockDep.Assets, LockAsset{Name: part.Asset.Name, URL: part.DownloadURL, SHA256: part.expectedSHA256()})
+	}
+	return lockDep
+}
+func (r *ResolvedDependency) carryOverLock(oldLock LockDependency) LockDependency {
+	// Used by commands that resolve without installing: checksums and what
+	// the last install recorded about the installed files still hold while
+	// the version is unchanged. A zero oldLock matches nothing.
+	r.useLockedChecksums(oldLock)
+	lockDep := r.lockDependency()
+	if oldLock.Hash != "" && sameVersion(oldLock.Hash, lockDep.Hash) {
+		lockDep.PackageVersion = oldLock.PackageVersion
+		lockDep.Installed = oldLock.Installed
+	}
+	return lockDep
+}
+func (pm *Manager) fetchDependency(ctx context.Context, resolved *ResolvedDependency) error {
+	var err error
+	switch {
+	case resolved.LocalPath != "":
+		err = pm.fetchLocalDependency(ctx, resolved)
+	case resolved.Type == "source":
+		err = pm.fetchSourceDependency(ctx, resolved)
+	case resolved.Type == "binary", resolved.Type == "file":
+		err = pm.fetchBinaryDependency(ctx, resolved)
+	default:
+		err = pm.fetchRepositoryDependency(ctx, resolved)
+	}
+	if err != nil {
+		return err
+	}
+
+	if resolved.Dependency.SaveChangelog {
+		return pm.saveChangelog(resolved)
+	}
+	return nil
+}
+func (pm *Manager) saveChangelog(resolved *ResolvedDependency) error {
+	if resolved.ReleaseNotes == "" {
+		fmt.Fprintf(pm.progress, "No release notes available for %s, skipping changelog\n", resolved.Name)
+		return nil
+	}
+
+	changelogPath := filepath.Join(pm.targetPath(resolved.ExpandedPath), fmt.Sprintf("CHANGELOG-%s.md", resolved.Name))
+	err := os.MkdirAll(filepath.Dir(changelogPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	content := fmt.Sprintf("# %s %s\n\n%s\n", resolved.Name, resolved.Version, strings.TrimSpace(resolved.ReleaseNotes))
+	err = os.WriteFile(changelogPath, []byte(content), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write changelog: %v", err)
+	}
+
+	fmt.Fprintf(pm.progress, "Saved release notes to %s\n", changelogPath)
+	resolved.Files = append(resolved.Files, changelogPath)
+	return nil
+}
+func (pm *Manager) fetchLocalDependency(ctx context.Context, resolved *ResolvedDependency) error {
+	if resolved.Asset != nil {
+		return pm.fetchBinaryDependency(ctx, resolved)
+	}
+
+	targetDir := pm.targetPath(resolved.ExpandedPath)
+	fmt.Fprintf(pm.progress, "Copying %s to %s...\n", resolved.LocalPath, targetDir)
+	return filepath.Walk(resolved.LocalPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(resolved.LocalPath, path)
+		if err != nil {
+			return err
+		}
+		targetPath := filepath.Join(targetDir, relPath)
+		if info.IsDir() {
+			return os.MkdirAll(targetPath, 0755)
+		}
+		err = copyFile(path, targetPath)
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %v", relPath, err)
+		}
+		resolved.Files = append(resolved.Files, targetPath)
+		return os.Chmod(targetPath, info.Mode().Perm())
+	})
+}
+func (pm *Manager) fetchSourceDependency(ctx context.Context, resolved *ResolvedDependency) error {
+	dep := resolved.Dependency
+	targetPath := pm.targetPath(resolved.ExpandedPath)
+
+	fmt.Fprintf(pm.progress, "Downloading source code (%s) from: %s\n", resolved.SourceFormat, resolved.DownloadURL)
+
+	var actualTargetPath string
+	var archiveName string
+
+	if dep.Filename != "" {
+		archiveName = pm.expandPathWithOptions(dep.Filename, resolved.Version, resolved.SourceFormat, dep.Extract)
+	} else {
+		archiveName = fmt.Sprintf("%s-%s.%s", resolved.Repo, strings.ReplaceAll(resolved.Version, "/", "-"), resolved.SourceFormat)
+	}
+
+	var tmpDir string
+	if dep.Extract {
+		var err error
+		tmpDir, err = pm.createTempDir()
+		if err != nil {
+			return fmt.Errorf("failed to create tmp directory: %v", err)
+		}
+		defer os.RemoveAll(tmpDir)
+		actualTargetPath = filepath.Join(tmpDir, archiveName)
+	} else {
+		actualTargetPath = filepath.Join(targetPath, archiveName)
+	}
+
+	err := pm.downloadResolved(ctx, resolved, actualTargetPath, downloadMode(resolved, dep.Extract))
+	if err != nil {
+		return fmt.Errorf("failed to download source code: %w", err)
+	}
+	if !dep.Extract {
+		resolved.Files = append(resolved.Files, actualTargetPath)
+	}
+
+	if dep.Extract {
+		tmpExtractDir := filepath.Join(tmpDir, "extracted")
+
+		pm.emit(Event{Kind: EventExtract, Dependency: resolved.Name, Version: resolved.Version, Path: actualTargetPath})
+		err = pm.extractArchive(actualTargetPath, tmpExtractDir)
+		if err != nil {
+			return fmt.Errorf("failed to extract source archive: %v", err)
+		}
+
+		targetDir := pm.targetPath(resolved.ExpandedPath)
+		err = os.MkdirAll(targetDir, 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create target directory: %v", err)
+		}
+
+		extractedDir, err := archiveRoot(tmpExtractDir)
+		if err != nil {
+			return fmt.Errorf("failed to read extracted directory: %v", err)
+		}
+
+		err = filepath.Walk(extractedDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			relPath, err := filepath.Rel(extractedDir, path)
+			if err != nil {
+				return err
+			}
+
+			if relPath == "." {
+				return nil
+			}
+
+			targetPath := filepath.Join(targetDir, relPath)
+
+			if info.IsDir() {
+				return os.MkdirAll(targetPath, info.Mode())
+			} else {
+				targetFileDir := filepath.Dir(targetPath)
+				err = os.MkdirAll(targetFileDir, 0755)
+				if err != nil {
+					return err
+				}
+				resolved.Files = append(resolved.Files, targetPath)
+				return movePath(path, targetPath)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to move extracted files: %v", err)
+		}
+
+		fmt.Fprintf(pm.progress, "Extracted source code to directory: %s\n", targetDir)
+	}
+
+	return nil
+}
+func (pm *Manager) fetchBinaryDependency(ctx context.Context, resolved *ResolvedDependency) error {
+	if len(resolved.Parts) > 0 {
+		for _, part := range resolved.Parts {
+			err := pm.fetchBinaryDependency(ctx, part)
+			resolved.Files = append(resolved.Files, part.Files...)
+			if err != nil {
+				return fmt.Errorf("%s: %v", part.Asset.Name, err)
+			}
+		}
+		return nil
+	}
+
+	dep := resolved.Dependency
+	assetName := resolved.Asset.Name
+	expandedPath := resolved.ExpandedPath
+	targetPath := pm.targetPath(expandedPath)
+	if dep.InstallPackage {
+		if systemPackageFormat(assetName) == "" {
+			return fmt.Errorf("install_package requires a .deb or .rpm asset, got %s", assetName)
+		}
+		// The package manager usually runs as root, so this needs an
+		// explicit opt-in on the command line, not just in the config.
+		if !pm.allowPackages {
+			return fmt.Errorf("install_package runs the system package manager; pass --allow-system-packages to allow it")
+		}
+	}
+
+	var actualTargetPath string
+	var tmpDir string
+	extracting := dep.Extract && (isArchiveFile(assetName) || compressedFileExtension(assetName) != "")
+	if extracting {
+		var err error
+		tmpDir, err = pm.createTempDir()
+		if err != nil {
+			return fmt.Errorf("failed to create tmp directory: %v", err)
+		}
+		defer os.RemoveAll(tmpDir)
+		actualTargetPath = filepath.Join(tmpDir, assetName)
+	} else if dep.Filename != "" {
+		actualTargetPath = filepath.Join(targetPath, dep.Filename)
+	} else {
+		actualTargetPath = filepath.Join(targetPath, assetName)
+	}
+
+	var err error
+	mode := downloadMode(resolved, extracting)
+	if resolved.LocalPath != "" {
+		err = pm.copyLocalFile(resolved.LocalPath, actualTargetPath, mode)
+	} else {
+		err = pm.downloadResolved(ctx, resolved, actualTargetPath, mode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download binary: %w", err)
+	}
+	if !extracting {
+		resolved.Files = append(resolved.Files, actualTargetPath)
+	}
+	if dep.Extract {
+		if compressedExt := compressedFileExtension(assetName); compressedExt != "" {
+			outputName := dep.Filename
+			if outputName == "" {
+				outputName = strings.TrimSuffix(assetName, compressedExt)
+			}
+			finalPath := filepath.Join(pm.targetPath(expandedPath), outputName)
+
+			pm.emit(Event{Kind: EventExtract, Dependency: resolved.Name, Version: resolved.Version, Path: actualTargetPath})
+			err = pm.decompressFile(actualTargetPath, finalPath, resolved.FileMode)
+			if err != nil {
+				return fmt.Errorf("failed to decompress file: %v", err)
+			}
+			fmt.Fprintf(pm.progress, "Decompressed single file as: %s\n", finalPath)
+			resolved.Files = append(resolved.Files, finalPath)
+		} else if isArchiveFile(assetName) {
+			tmpExtractDir := filepath.Join(tmpDir, "extracted")
+
+			pm.emit(Event{Kind: EventExtract, Dependency: resolved.Name, Version: resolved.Version, Path: actualTargetPath})
+			err = pm.extractArchive(actualTargetPath, tmpExtractDir)
+			if err != nil {
+				return fmt.Errorf("failed to extract archive: %v", err)
+			}
+
+			var extractedFiles []string
+			err = filepath.Walk(tmpExtractDir, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !info.IsDir() && path != tmpExtractDir {
+					extractedFiles = append(extractedFiles, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to walk extracted files: %v", err)
+			}
+
+			fmt.Fprintf(pm.progress, "Found %d files in archive\n", len(extractedFiles))
+
+			if dep.Filename != "" {
+				if len(extractedFiles) > 1 {
+					return fmt.Errorf("filename specified but archive contains %d files (expected 1). Remove filename to extract all files to directory", len(extractedFiles))
+				}
+				if len(extractedFiles) == 0 {
+					return fmt.Errorf("no files found in archive")
+				}
+
+				targetDir := pm.targetPath(expandedPath)
+				err = os.MkdirAll(targetDir, 0755)
+				if err != nil {
+					return fmt.Errorf("failed to create target directory: %v", err)
+				}
+
+				finalPath := filepath.Join(targetDir, dep.Filename)
+				err = movePath(extractedFiles[0], finalPath)
+				if err != nil {
+					return fmt.Errorf("failed to move extracted file: %v", err)
+				}
+				if dep.Mode != "" || dep.Executable != nil {
+					err = os.Chmod(finalPath, resolved.FileMode)
+					if err != nil {
+						return fmt.Errorf("failed to set permissions: %v", err)
+					}
+				}
+				fmt.Fprintf(pm.progress, "Extracted single file as: %s\n", finalPath)
+				resolved.Files = append(resolved.Files, finalPath)
+			} else {
+				targetDir := pm.targetPath(expandedPath)
+				err = os.MkdirAll(targetDir, 0755)
+				if err != nil {
+					return fmt.Errorf("failed to create target directory: %v", err)
+				}
+
+				baseDir := tmpExtractDir
+				if dep.Flatten {
+					baseDir, err = archiveRoot(tmpExtractDir)
+					if err != nil {
+						return fmt.Errorf("failed to read extracted directory: %v", err)
+					}
+				}
+
+				for _, file := range extractedFiles {
+					relPath, err := filepath.Rel(baseDir, file)
+					if err != nil {
+						return fmt.Errorf("failed to get relative path for %s: %v", file, err)
+					}
+
+					finalPath := filepath.Join(targetDir, relPath)
+					finalDir := filepath.Dir(finalPath)
+
+					err = os.MkdirAll(finalDir, 0755)
+					if err != nil {
+						return fmt.Errorf("failed to create directory %s: %v", finalDir, err)
+					}
+
+					err = movePath(file, finalPath)
+					if err != nil {
+						return fmt.Errorf("failed to move extracted file %s: %v", relPath, err)
+					}
+					resolved.Files = append(resolved.Files, finalPath)
+				}
+				fmt.Fprintf(pm.progress, "Extracted %d files to directory: %s\n", len(extractedFiles), targetDir)
+			}
+		} else {
+			fmt.Fprintf(pm.progress, "Warning: extract flag is set but %s is not a supported archive format\n", assetName)
+		}
+	}
+	if dep.InstallPackage {
+		err = pm.installSystemPackage(ctx, resolved, actualTargetPath)
+		if err != nil {
+			return fmt.Errorf("failed to install package: %v", err)
+		}
+	}
+
+	return nil
+}
+func systemPackageFormat(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".deb"):
+		return "deb"
+	case strings.HasSuffix(lower, ".rpm"):
+		return "rpm"
+	}
+	return ""
+}
+func (pm *Manager) installSystemPackage(ctx context.Context, resolved *ResolvedDependency, packagePath string) error {
+	packagePath, err := filepath.Abs(packagePath)
+	if err != nil {
+		return err
+	}
+
+	var query, install []string
+	switch systemPackageFormat(packagePath) {
+	case "deb":
+		query = []string{"dpkg-deb", "--field", packagePath, "Version"}
+		// apt resolves the package's own dependencies, dpkg does not.
+		if _, err := exec.LookPath("apt-get"); err == nil {
+			install = []string{"apt-get", "install", "-y", packagePath}
+		} else {
+			install = []string{"dpkg", "-i", packagePath}
+		}
+	case "rpm":
+		query = []string{"rpm", "-qp", "--queryformat", "%{VERSION}-%{RELEASE}", packagePath}
+		if _, err := exec.LookPath("dnf"); err == nil {
+			install = []string{"dnf", "install", "-y", packagePath}
+		} else {
+			install = []string{"rpm", "-U", "--replacepkgs", packagePath}
+		}
+	}
+
+	output, err := exec.CommandContext(ctx, query[0], query[1:]...).Output()
+	if err != nil {
+		return fmt.Errorf("failed to read package version with %s: %v", query[0], err)
+	}
+	packageVersion := strings.TrimSpace(string(output))
+
+	if os.Geteuid() != 0 {
+		if _, err := exec.LookPath("sudo"); err == nil {
+			install = append([]string{"sudo"}, install...)
+		}
+	}
+	fmt.Fprintf(pm.progress, "📦 Installing package %s (%s) with %s...\n", filepath.Base(packagePath), packageVersion, strings.Join(install, " "))
+	cmd := exec.CommandContext(ctx, install[0], install[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = pm.progress
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("%s failed: %v", install[0], err)
+	}
+	resolved.PackageVersion = packageVersion
+	return nil
+}
+func archiveRoot(dir string) (string, error) {
+	// Archives that wrap everything in one top-level directory (the usual
+	// "name-v1.2.3/" layout) are unpacked from inside that directory.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
+func (pm *Manager) createTempDir() (string, error) {
+	// Every download gets its own directory, so concurrent installs never
+	// share archive or extraction paths.
+	root := pm.targetPath(pm.tmpRoot)
+	err := os.MkdirAll(root, 0755)
+	if err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(root, "fracture-*")
+}
+func movePath(source, target string) error {
+	// The temp directory may be on another filesystem, where a rename
+	// fails and the tree has to be copied instead.
+	err := os.Rename(source, target)
+	if err == nil {
+		return nil
+	}
+	if _, statErr := os.Lstat(source); statErr != nil {
+		return err
+	}
+	copyErr := copyTree(source, target)
+	if copyErr != nil {
+		return fmt.Errorf("%v; copying instead also failed: %v", err, copyErr)
+	}
+	return os.RemoveAll(source)
+}
+func copyTree(source, target string) error {
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		destination := filepath.Join(target, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(destination, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(destination)
+			return os.Symlink(link, destination)
+		default:
+			err = copyFile(path, destination)
+			if err != nil {
+				return err
+			}
+			return os.Chmod(destination, info.Mode().Perm())
+		}
+	})
+}
+func (pm *Manager) fetchRepositoryDependency(ctx context.Context, resolved *ResolvedDependency) error {
+	dep := resolved.Dependency
+	targetPath := pm.targetPath(resolved.ExpandedPath)
+
+	err := pm.cloneOrUpdateRepo(ctx, dep.Source, targetPath, resolved.Version, dep.Private, dep.Sparse)
+	if err != nil {
+		return fmt.Errorf("failed to install %s: %v", resolved.Name, err)
+	}
+	resolved.Files = append(resolved.Files, targetPath)
+
+	head, err := pm.getCheckedOutCommit(ctx, targetPath)
+	if err != nil {
+		return err
+	}
+	if head != resolved.Version {
+		return fmt.Errorf("checked out commit %s does not match resolved commit %s", head, resolved.Version)
+	}
+	return nil
+}
+func (pm *Manager) expandLockedCommit(ctx context.Context, repoPath, lockedHash string) (string, error) {
+	if isFullCommitHash(lockedHash) {
+		return lockedHash, nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "rev-parse", "--verify", "--quiet", lockedHash+"^{commit}")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("truncated lock hash %s is ambiguous or unknown in %s; run 'fracture lock' to record the full commit SHA", lockedHash, repoPath)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+func isCommitHash(value string) bool {
+	// SHA-1 repositories use 40 characters, SHA-256 ones 64.
+	if len(value) < 7 || len(value) > 64 {
+		return false
+	}
+	return isHex(value)
+}
+func isFullCommitHash(value string) bool {
+	return (len(value) == 40 || len(value) == 64) && isCommitHash(value)
+}
+func isHex(value string) bool {
+	for _, c := range value {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+func ShortHash(value string) string {
+	if len(value) == 40 && isCommitHash(value) {
+		return value[:8]
+	}
+	// Content hashes of local sources.
+	if len(value) == 64 && isHex(value) {
+		return value[:12]
+	}
+	return value
+}
+func sameVersion(locked, resolved string) bool {
+	if locked == resolved {
+		return true
+	}
+	if len(locked) < len(resolved) && isFullCommitHash(resolved) && isCommitHash(locked) {
+		return strings.HasPrefix(resolved, locked)
+	}
+	return false
+}
+func (pm *Manager) getCheckedOutCommit(ctx context.Context, repoPath string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "rev-parse", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD of %s: %v", repoPath, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+func sortedFailureNames(failures map[string]error) []string {
+	names := make([]string, 0, len(failures))
+	for name := range failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+func printFailureSummary(w io.Writer, action string, failures map[string]error, total int) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "──────── Summary ────────")
+	fmt.Fprintf(w, "❌ %d of %d dependencies failed to %s:\n", len(failures), total, action)
+	for _, name := range sortedFailureNames(failures) {
+		fmt.Fprintf(w, "  - %s: %v\n", name, failures[name])
+	}
+}
+func staleLockEntries(lock LockFile, deps DepsFile) []string {
+	var stale []string
+	for name := range lock {
+		if _, exists := deps[name]; !exists {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+func (pm *Manager) applyLockPruning(previousLock, lock LockFile, deps DepsFile) {
+	// Entries of dependencies removed from the config are only dropped with
+	// --prune-lock; otherwise install, update and lock all carry them over.
+	stale := staleLockEntries(previousLock, deps)
+	if len(stale) == 0 {
+		return
+	}
+	if !pm.pruneLock {
+		for _, name := range stale {
+			lock[name] = previousLock[name]
+		}
+		fmt.Fprintf(pm.progress, "📝 %s still lists dependencies that are no longer in %s: %s (pass --prune-lock to remove them)\n", pm.lockPath, pm.configPath, strings.Join(stale, ", "))
+		return
+	}
+
+	for _, name := range stale {
+		delete(lock, name)
+		fmt.Fprintf(pm.progress, "🧹 Removed %s from %s: no longer in %s\n", name, pm.lockPath, pm.configPath)
+	}
+	if !pm.pruneFiles {
+		return
+	}
+	for _, name := range stale {
+		err := pm.removePrunedPath(name, previousLock[name], lock)
+		if err != nil {
+			fmt.Fprintf(pm.progress, "Warning: keeping files of %s: %v\n", name, err)
+		}
+	}
+}
+func (pm *Manager) removePrunedPath(name string, lockDep LockDependency, remaining LockFile) error {
+	// The lock only records the dependency's path, which can be a directory
+	// shared with other tools, so only paths that are clearly its own go.
+	target := pm.targetPath(lockDep.Path)
+	rel, err := filepath.Rel(pm.workDir, target)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside the project directory", lockDep.Path)
+	}
+	for otherName, other := range remaining {
+		otherPath := pm.targetPath(other.Path)
+		if pathsOverlap(target, otherPath) {
+			return fmt.Errorf("%s overlaps the path of %s", lockDep.Path, otherName)
+		}
+	}
+	if _, err := os.Lstat(target); os.IsNotExist(err) {
+		return nil
+	}
+	err = os.RemoveAll(target)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(pm.progress, "🧹 Deleted %s (%s)\n", lockDep.Path, name)
+	return nil
+}
+func pathsOverlap(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if a == b {
+		return true
+	}
+	sep := string(filepath.Separator)
+	return strings.HasPrefix(a, b+sep) || strings.HasPrefix(b, a+sep)
+}
+func (pm *Manager) checkPathCollisions(deps DepsFile) error {
+	// Repositories and extracted archives own their whole path; other
+	// dependencies only the file they write into it, which is only known
+	// up front when filename (or an exact asset_name) is set.
+	owners := make(map[string]string)
+	files := make(map[string]string)
+	var collisions []string
+	for _, name := range sortedDependencyNames(deps) {
+		dep := deps[name]
+		if dep.Path == "" || !pm.isPlatformSupported(dep) {
+			continue
+		}
+		target := dep.Path
+		if dep.Version != "" {
+			target = strings.ReplaceAll(target, "@VERSION", dep.Version)
+		}
+		if hasPathVariables(target) {
+			continue
+		}
+		target = filepath.Clean(pm.targetPath(target))
+		if pm.dependencyType(name, dep) == "repository" || dep.Extract {
+			if other, exists := owners[target]; exists {
+				collisions = append(collisions, fmt.Sprintf("%s and %s both install to %s", other, name, dep.Path))
+			} else {
+				owners[target] = name
+			}
+			continue
+		}
+		fileName := dep.Filename
+		if fileName == "" && !isAssetGlob(dep.AssetName) {
+			fileName = dep.AssetName
+		}
+		if fileName == "" || hasPathVariables(fileName) {
+			continue
+		}
+		file := filepath.Join(target, fileName)
+		if other, exists := files[file]; exists {
+			collisions = append(collisions, fmt.Sprintf("%s and %s both install %s", other, name, filepath.Join(dep.Path, fileName)))
+		} else {
+			files[file] = name
+		}
+	}
+	// A file written into a path another dependency owns is lost the next
+	// time that dependency is installed.
+	var written []string
+	for file := range files {
+		written = append(written, file)
+	}
+	sort.Strings(written)
+	for _, file := range written {
+		if owner, exists := owners[filepath.Dir(file)]; exists {
+			collisions = append(collisions, fmt.Sprintf("%s installs into the path of %s", files[file], owner))
+		}
+	}
+	if len(collisions) > 0 {
+		return fmt.Errorf("conflicting paths: %s", strings.Join(collisions, "; "))
+	}
+	return nil
+}
+func hasPathVariables(path string) bool {
+	for _, variable := range []string{"@VERSION", "@TIMESTAMP", "@ASSET_EXTENSION"} {
+		if strings.Contains(path, variable) {
+			return true
+		}
+	}
+	return false
+}
+func printVersionChanges(w io.Writer, previousLock, lock LockFile, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	width := 0
+	for _, name := range names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	fmt.Fprintln(w, "\n──────── Versions ────────")
+	for _, name := range names {
+		current := lock[name]
+		previous, existed := previousLock[name]
+		switch {
+		case !existed:
+			fmt.Fprintf(w, "  %-*s  (new) -> %s%s\n", width, name, ShortHash(current.Version), publishedSuffix(current))
+		case sameVersion(previous.Hash, current.Hash):
+			fmt.Fprintf(w, "  %-*s  %s (unchanged)%s\n", width, name, ShortHash(current.Version), publishedSuffix(current))
+		default:
+			fmt.Fprintf(w, "  %-*s  %s -> %s%s\n", width, name, ShortHash(previous.Version), ShortHash(current.Version), publishedSuffix(current))
+		}
+	}
+}
+func (pm *Manager) printChangelogs(ctx context.Context, deps DepsFile, previousLock, lock LockFile, names []string) {
+	sort.Strings(names)
+	for _, name := range names {
+		previous, existed := previousLock[name]
+		current := lock[name]
+		dep := deps[name]
+		if !existed || sameVersion(previous.Hash, current.Hash) {
+			continue
+		}
+		// Only GitHub releases have notes; refs and local sources don't.
+		if (current.Type != "binary" && current.Type != "source") || current.Ref != "" {
+			continue
+		}
+		if _, isLocal := localSourcePath(dep.Source); isLocal {
+			continue
+		}
+		releases, err := pm.releasesBetween(ctx, dep, previous, current)
+		if err != nil {
+			fmt.Fprintf(pm.progress, "Warning: failed to collect release notes for %s: %v\n", name, err)
+			continue
+		}
+
+		fmt.Fprintf(pm.out, "\n──────── Changelog: %s %s -> %s ────────\n", name, previous.Version, current.Version)
+		for _, release := range releases {
+			body := strings.TrimSpace(release.Body)
+			if body == "" {
+				body = "(no release notes)"
+			}
+			heading := release.TagName
+			if published, err := time.Parse(time.RFC3339, release.PublishedAt); err == nil {
+				heading += published.Format(" (2006-01-02)")
+			}
+			fmt.Fprintf(pm.out, "\n## %s\n\n%s\n", heading, body)
+		}
+	}
+}
+func (pm *Manager) releasesBetween(ctx context.Context, dep Dependency, previous, current LockDependency) ([]GitHubRelease, error) {
+	owner, repo, err := pm.extractRepoInfo(dep.Source)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := pm.listReleases(ctx, owner, repo, dep.Private)
+	if err != nil {
+		return nil, err
+	}
+
+	// The API lists releases newest first. Everything from the new release
+	// down to (not including) the locked one is part of the jump; when the
+	// locked tag is gone, its publish time marks the end instead.
+	var between []GitHubRelease
+	started := false
+	for _, release := range releases {
+		if release.Draft || !strings.HasPrefix(release.TagName, dep.TagPrefix) {
+			continue
+		}
+		if release.TagName == current.Version {
+			started = true
+		}
+		if !started {
+			continue
+		}
+		if release.TagName == previous.Version {
+			break
+		}
+		if previous.PublishedAt != "" && release.PublishedAt != "" && release.PublishedAt <= previous.PublishedAt {
+			break
+		}
+		between = append(between, release)
+	}
+	if len(between) == 0 {
+		return nil, fmt.Errorf("release %s not found in %s/%s", current.Version, owner, repo)
+	}
+	return between, nil
+}
+func publishedSuffix(lockDep LockDependency) string {
+	published, err := time.Parse(time.RFC3339, lockDep.PublishedAt)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(", published %s", published.Format("2006-01-02"))
+}
+func failedDependenciesError(action string, failures map[string]error, total int) error {
+	names := sortedFailureNames(failures)
+	return fmt.Errorf("failed to %s %d of %d dependencies: %s", action, len(failures), total, strings.Join(names, ", "))
+}
+func installOrder(deps DepsFile) ([]string, error) {
+	// Dependencies are installed after everything they require. Names are
+	// visited in sorted order so the result is stable between runs.
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(deps))
+	var order, stack []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for i, entry := range stack {
+				if entry == name {
+					start = i
+					break
+				}
+			}
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(stack[start:], " -> "), name)
+		}
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, required := range deps[name].Requires {
+			if _, exists := deps[required]; !exists {
+				return fmt.Errorf("dependency %s requires unknown dependency %s", name, required)
+			}
+			if err := visit(required); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+func failedRequirement(dep Dependency, failures map[string]error) string {
+	for _, required := range dep.Requires {
+		if _, failed := failures[required]; failed {
+			return required
+		}
+	}
+	return ""
+}
+func (pm *Manager) Install(ctx context.Context) error {
+	fmt.Fprintln(pm.progress, "🚀 Starting dependency installation...")
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	_, err = pm.expandVersions(ctx, deps, lock, false)
+	if err != nil {
+		return err
+	}
+	err = pm.requireGit(deps)
+	if err != nil {
+		return err
+	}
+	err = pm.checkPathCollisions(deps)
+	if err != nil {
+		return err
+	}
+
+	if pm.reportPath != "" {
+		pm.report = make(InstallReport)
+	}
+	if pm.offline {
+		pm.vendor, err = pm.loadVendorManifest()
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %v", pm.vendorPath, err)
+		}
+	}
+	order, err := installOrder(deps)
+	if err != nil {
+		return err
+	}
+	pm.installLock = lock
+	defer func() { pm.installLock = nil }()
+	newLock := make(LockFile)
+	hasUpdates := false
+	installedCount, skippedCount := 0, 0
+	failures := make(map[string]error)
+	for _, name := range order {
+		dep := deps[name]
+		if ctx.Err() != nil {
+			break
+		}
+		if required := failedRequirement(dep, failures); required != "" {
+			err := fmt.Errorf("requires %s, which failed to install", required)
+			fmt.Fprintf(pm.progress, "⏭️  Skipping %s: %v\n", name, err)
+			failures[name] = err
+			if oldLock, exists := lock[name]; exists {
+				newLock[name] = oldLock
+			}
+			pm.report.add(name, ReportEntry{Type: pm.dependencyType(name, dep), Status: "failed", Error: err.Error()})
+			continue
+		}
+		if pm.skipUnsupported(name, dep) {
+			if oldLock, exists := lock[name]; exists {
+				newLock[name] = oldLock
+			}
+			pm.report.add(name, ReportEntry{Type: pm.dependencyType(name, dep), Status: "skipped", Reason: fmt.Sprintf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)})
+			skippedCount++
+			continue
+		}
+		if oldLock, exists := lock[name]; exists && pm.onlyMissing {
+			if _, err := os.Stat(pm.targetPath(oldLock.Path)); err == nil {
+				fmt.Fprintf(pm.progress, "⏭️  Skipping %s: already present at %s\n", name, oldLock.Path)
+				newLock[name] = oldLock
+				pm.report.add(name, ReportEntry{Type: oldLock.Type, Status: "skipped", Version: oldLock.Version, Path: oldLock.Path, Hash: oldLock.Hash, Reason: "already present"})
+				skippedCount++
+				continue
+			}
+		}
+		if satisfied, installed := pm.installedVersionSatisfies(ctx, name, dep); satisfied {
+			fmt.Fprintf(pm.progress, "⏭️  Skipping %s: installed version %s satisfies %s\n", name, installed, dep.SatisfiedIfVersion.Constraint)
+			if oldLock, exists := lock[name]; exists {
+				newLock[name] = oldLock
+			}
+			pm.report.add(name, ReportEntry{Type: pm.dependencyType(name, dep), Status: "skipped", Reason: fmt.Sprintf("installed version %s satisfies %s", installed, dep.SatisfiedIfVersion.Constraint)})
+			skippedCount++
+			continue
+		}
+		var lockDep LockDependency
+		if pm.offline {
+			lockDep, err = pm.installFromLock(ctx, name, dep, lock)
+		} else {
+			oldLock, exists := lock[name]
+			lockDep, err = pm.installDependency(ctx, name, pinToLock(dep, oldLock, exists))
+		}
+		if err != nil {
+			pm.emit(Event{Kind: EventFailed, Dependency: name, Err: err})
+			failures[name] = err
+			if oldLock, exists := lock[name]; exists {
+				newLock[name] = oldLock
+			}
+			pm.report.add(name, ReportEntry{Type: pm.dependencyType(name, dep), Status: "failed", Error: err.Error()})
+			continue
+		}
+		if oldLock, exists := lock[name]; exists {
+			if !sameVersion(oldLock.Hash, lockDep.Hash) {
+				fmt.Fprintf(pm.progress, "📦 Update available for %s: %s -> %s\n", name, ShortHash(oldLock.Hash), ShortHash(lockDep.Hash))
+				hasUpdates = true
+			}
+		} else {
+			hasUpdates = true
+		}
+
+		newLock[name] = lockDep
+		installedCount++
+	}
+	if !pm.offline && !pm.noLock {
+		pm.applyLockPruning(lock, newLock, deps)
+		err = pm.saveLockFile(newLock)
+		if err != nil {
+			return fmt.Errorf("failed to save %s: %v", pm.lockPath, err)
+		}
+	}
+
+	if pm.report != nil {
+		err = pm.saveReport()
+		if err != nil {
+			return fmt.Errorf("failed to save %s: %v", pm.reportPath, err)
+		}
+		fmt.Fprintf(pm.progress, "📝 Install report written to %s\n", pm.reportPath)
+	}
+
+	if hasUpdates {
+		fmt.Fprintln(pm.out, "📋 Updates available! Run 'fracture update' to update.")
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted")
+	}
+	fmt.Fprintf(pm.out, "📊 %d installed, %d skipped, %d failed\n", installedCount, skippedCount, len(failures))
+	if len(failures) > 0 {
+		printFailureSummary(pm.out, "install", failures, len(deps))
+		return failedDependenciesError("install", failures, len(deps))
+	}
+
+	fmt.Fprintln(pm.out, "✅ Installation completed!")
+	return nil
+}
+func (pm *Manager) reportInstalled(resolved *ResolvedDependency, lockDep LockDependency) {
+	if pm.report == nil {
+		return
+	}
+	entry := ReportEntry{
+		Type:    resolved.Type,
+		Status:  "installed",
+		Version: lockDep.Version,
+		Asset:   lockDep.Asset,
+		Path:    resolved.ExpandedPath,
+		Hash:    lockDep.Hash,
+	}
+	for _, file := range resolved.Files {
+		size, err := pathSize(file)
+		if err != nil {
+			fmt.Fprintf(pm.progress, "Warning: failed to measure %s for the report: %v\n", file, err)
+		}
+		entry.Size += size
+		entry.Files = append(entry.Files, pm.relativePath(file))
+	}
+	if len(resolved.Files) == 1 {
+		if info, err := os.Stat(resolved.Files[0]); err == nil && info.Mode().IsRegular() {
+			entry.SHA256, _ = fileSHA256(resolved.Files[0])
+		}
+	}
+	pm.report.add(resolved.Name, entry)
+}
+func (r InstallReport) add(depName string, entry ReportEntry) {
+	if r == nil {
+		return
+	}
+	r[depName] = entry
+}
+func (pm *Manager) saveReport() error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(pm.report)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(pm.targetPath(pm.reportPath), buf.Bytes(), 0644)
+}
+func (pm *Manager) relativePath(path string) string {
+	rel, err := filepath.Rel(pm.workDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+func pathSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+func (pm *Manager) Update(ctx context.Context, dependencyName, version string) error {
+	fmt.Fprintln(pm.progress, "🔄 Starting dependency update...")
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	versioned, err := pm.expandVersions(ctx, deps, lock, true)
+	if err != nil {
+		return err
+	}
+	err = pm.checkPathCollisions(deps)
+	if err != nil {
+		return err
+	}
+	previousLock := make(LockFile, len(lock))
+	for name, lockDep := range lock {
+		previousLock[name] = lockDep
+	}
+	pm.updateLock = previousLock
+	defer func() { pm.updateLock = nil }()
+	var updated []string
+	skipped := 0
+	failures := make(map[string]error)
+	if versionNames, exists := versioned[dependencyName]; exists {
+		if version != "" {
+			return fmt.Errorf("%s installs several releases, a single version cannot be selected", dependencyName)
+		}
+		for _, name := range versionNames {
+			dep := deps[name]
+			if ctx.Err() != nil {
+				break
+			}
+			if pm.skipUnsupported(name, dep) {
+				skipped++
+				continue
+			}
+			if isPinned(dep) {
+				fmt.Fprintf(pm.progress, "⏭️  Skipping %s: %s\n", name, pinDescription(dep))
+				skipped++
+				continue
+			}
+			fmt.Fprintf(pm.progress, "Updating %s...\n", name)
+			lockDep, err := pm.installDependency(ctx, name, dep)
+			if errors.Is(err, errUpdateDeclined) {
+				fmt.Fprintf(pm.progress, "⏭️  Skipping %s: update declined\n", name)
+				skipped++
+				continue
+			}
+			if err != nil {
+				pm.emit(Event{Kind: EventFailed, Dependency: name, Err: err})
+				failures[name] = err
+				continue
+			}
+			lock[name] = lockDep
+			updated = append(updated, name)
+		}
+	} else if dependencyName != "" {
+		dep, exists := deps[dependencyName]
+		if !exists {
+			return fmt.Errorf("dependency %s not found", dependencyName)
+		}
+		err = pm.requireGit(DepsFile{dependencyName: dep})
+		if err != nil {
+			return err
+		}
+
+		if pm.skipUnsupported(dependencyName, dep) {
+			return nil
+		}
+
+		if version == "" && isPinned(dep) {
+			fmt.Fprintf(pm.progress, "⏭️  Skipping %s: %s, pass a version to update it anyway\n", dependencyName, pinDescription(dep))
+			return nil
+		}
+		// An explicit version replaces the configured ref or pin for this
+		// update only.
+		if version != "" && dep.Ref != "" {
+			dep.Ref = version
+		} else if version != "" {
+			dep.Version = version
+		}
+		fmt.Fprintf(pm.progress, "Updating %s...\n", dependencyName)
+		lockDep, err := pm.installDependency(ctx, dependencyName, dep)
+		if errors.Is(err, errUpdateDeclined) {
+			fmt.Fprintf(pm.progress, "⏭️  Skipping %s: update declined\n", dependencyName)
+			return nil
+		}
+		if err != nil {
+			pm.emit(Event{Kind: EventFailed, Dependency: dependencyName, Err: err})
+			return fmt.Errorf("failed to update %s: %v", dependencyName, err)
+		}
+		lock[dependencyName] = lockDep
+		updated = append(updated, dependencyName)
+	} else {
+		order, err := installOrder(deps)
+		if err != nil {
+			return err
+		}
+		err = pm.requireGit(deps)
+		if err != nil {
+			return err
+		}
+		for _, name := range order {
+			dep := deps[name]
+			if ctx.Err() != nil {
+				break
+			}
+			if required := failedRequirement(dep, failures); required != "" {
+				err := fmt.Errorf("requires %s, which failed to update", required)
+				fmt.Fprintf(pm.progress, "⏭️  Skipping %s: %v\n", name, err)
+				failures[name] = err
+				continue
+			}
+			if pm.skipUnsupported(name, dep) {
+				skipped++
+				continue
+			}
+			if isPinned(dep) {
+				fmt.Fprintf(pm.progress, "⏭️  Skipping %s: %s\n", name, pinDescription(dep))
+				skipped++
+				continue
+			}
+			if satisfied, installed := pm.installedVersionSatisfies(ctx, name, dep); satisfied {
+				fmt.Fprintf(pm.progress, "⏭️  Skipping %s: installed version %s satisfies %s\n", name, installed, dep.SatisfiedIfVersion.Constraint)
+				skipped++
+				continue
+			}
+			fmt.Fprintf(pm.progress, "Updating %s...\n", name)
+			lockDep, err := pm.installDependency(ctx, name, dep)
+			if errors.Is(err, errUpdateDeclined) {
+				fmt.Fprintf(pm.progress, "⏭️  Skipping %s: update declined\n", name)
+				skipped++
+				continue
+			}
+			if err != nil {
+				pm.emit(Event{Kind: EventFailed, Dependency: name, Err: err})
+				failures[name] = err
+				continue
+			}
+			lock[name] = lockDep
+			updated = append(updated, name)
+		}
+	}
+	if !pm.noLock {
+		pm.applyLockPruning(previousLock, lock, deps)
+		err = pm.saveLockFile(lock)
+		if err != nil {
+			return fmt.Errorf("failed to save %s: %v", pm.lockPath, err)
+		}
+	}
+
+	printVersionChanges(pm.out, previousLock, lock, updated)
+	if pm.changelog {
+		pm.printChangelogs(ctx, deps, previousLock, lock, updated)
+	}
+	changed := 0
+	for _, name := range updated {
+		if previous, existed := previousLock[name]; !existed || !sameVersion(previous.Hash, lock[name].Hash) {
+			changed++
+		}
+	}
+	fmt.Fprintf(pm.out, "📊 %d updated, %d unchanged, %d skipped, %d failed\n", changed, len(updated)-changed, skipped, len(failures))
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted")
+	}
+	if len(failures) > 0 {
+		printFailureSummary(pm.out, "update", failures, len(deps))
+		return failedDependenciesError("update", failures, len(deps))
+	}
+
+	fmt.Fprintln(pm.out, "✅ Update completed!")
+	return nil
+}
+func (pm *Manager) Lock(ctx context.Context) error {
+	fmt.Fprintln(pm.progress, "🔒 Resolving dependency versions...")
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	// Nothing is downloaded here, so raw files take their content hash
+	// from the lock instead of being fetched to hash them.
+	pm.probeLock = lock
+	defer func() { pm.probeLock = nil }()
+	_, err = pm.expandVersions(ctx, deps, lock, true)
+	if err != nil {
+		return err
+	}
+	err = pm.requireGit(deps)
+	if err != nil {
+		return err
+	}
+
+	newLock := make(LockFile)
+	failures := make(map[string]error)
+	for _, name := range sortedDependencyNames(deps) {
+		dep := deps[name]
+		if ctx.Err() != nil {
+			break
+		}
+		if pm.skipUnsupported(name, dep) {
+			if oldLock, exists := lock[name]; exists {
+				newLock[name] = oldLock
+			}
+			continue
+		}
+		depCtx, cancel := pm.dependencyContext(ctx, name)
+		resolved, err := pm.resolveDependency(depCtx, name, dep)
+		if err != nil {
+			err = pm.contextError(depCtx, err)
+		}
+		cancel()
+		if err != nil {
+			fmt.Fprintf(pm.progress, "❌ Resolution error for %s: %v\n", name, err)
+			failures[name] = err
+			if oldLock, exists := lock[name]; exists {
+				newLock[name] = oldLock
+			}
+			continue
+		}
+		if resolved.Type == "file" && resolved.Version == "" {
+			fmt.Fprintf(pm.progress, "⏭️  Not locking %s: a raw file is versioned by its content, which is recorded by the next install\n", name)
+			continue
+		}
+		lockDep := resolved.carryOverLock(lock[name])
+		if oldLock, exists := lock[name]; exists && !sameVersion(oldLock.Hash, lockDep.Hash) {
+			fmt.Fprintf(pm.progress, "📦 %s: %s -> %s\n", name, ShortHash(oldLock.Hash), ShortHash(lockDep.Hash))
+		} else {
+			fmt.Fprintf(pm.progress, "✓ Resolved: %s (version: %s)\n", name, ShortHash(lockDep.Version))
+		}
+
+		newLock[name] = lockDep
+	}
+	pm.applyLockPruning(lock, newLock, deps)
+	err = pm.saveLockFile(newLock)
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %v", pm.lockPath, err)
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted")
+	}
+	if len(failures) > 0 {
+		printFailureSummary(pm.out, "resolve", failures, len(deps))
+		return failedDependenciesError("resolve", failures, len(deps))
+	}
+
+	fmt.Fprintf(pm.progress, "✅ Lock file %s updated (nothing was downloaded)\n", pm.lockPath)
+	return nil
+}
+func (pm *Manager) Resolve(ctx context.Context, dependencyName string) (LockDependency, error) {
+	// Resolve is what lock does for a single dependency: it talks to the
+	// source but downloads nothing and leaves the lock file alone.
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return LockDependency{}, fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return LockDependency{}, fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	pm.probeLock = lock
+	defer func() { pm.probeLock = nil }()
+	_, err = pm.expandVersions(ctx, deps, lock, true)
+	if err != nil {
+		return LockDependency{}, err
+	}
+	dep, exists := deps[dependencyName]
+	if !exists {
+		return LockDependency{}, fmt.Errorf("dependency %s not found", dependencyName)
+	}
+	err = pm.requireGit(DepsFile{dependencyName: dep})
+	if err != nil {
+		return LockDependency{}, err
+	}
+
+	depCtx, cancel := pm.dependencyContext(ctx, dependencyName)
+	defer cancel()
+	resolved, err := pm.resolveDependency(depCtx, dependencyName, dep)
+	if err != nil {
+		return LockDependency{}, pm.contextError(depCtx, err)
+	}
+	return resolved.lockDependency(), nil
+}
+func (pm *Manager) VerifyOnly(ctx context.Context) error {
+	fmt.Fprintln(pm.progress, "🔎 Checking that every dependency resolves...")
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	pm.probeLock = lock
+	defer func() { pm.probeLock = nil }()
+	_, err = pm.expandVersions(ctx, deps, lock, false)
+	if err != nil {
+		return err
+	}
+	err = pm.requireGit(deps)
+	if err != nil {
+		return err
+	}
+	err = pm.checkPathCollisions(deps)
+	if err != nil {
+		return err
+	}
+
+	// Resolution already looks up the release or ref and picks the asset,
+	// which is everything install needs before it starts downloading.
+	failures := make(map[string]error)
+	for _, name := range sortedDependencyNames(deps) {
+		dep := deps[name]
+		if ctx.Err() != nil {
+			break
+		}
+		if pm.skipUnsupported(name, dep) {
+			continue
+		}
+		oldLock, exists := lock[name]
+		depCtx, cancel := pm.dependencyContext(ctx, name)
+		resolved, err := pm.resolveDependency(depCtx, name, pinToLock(dep, oldLock, exists))
+		if err != nil {
+			err = pm.contextError(depCtx, err)
+		}
+		cancel()
+		if err != nil {
+			fmt.Fprintf(pm.progress, "❌ %s: %v\n", name, err)
+			failures[name] = err
+			continue
+		}
+		fmt.Fprintf(pm.progress, "✓ %s: %s\n", name, describeResolved(resolved))
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted")
+	}
+	if len(failures) > 0 {
+		printFailureSummary(pm.out, "resolve", failures, len(deps))
+		return failedDependenciesError("resolve", failures, len(deps))
+	}
+	fmt.Fprintln(pm.progress, "✅ All dependencies resolve (nothing was installed)")
+	return nil
+}
+func (pm *Manager) Verify(ctx context.Context) error {
+	// Only the lock file and the disk are read, so this works offline and
+	// writes nothing but the hash cache.
+	fmt.Fprintf(pm.progress, "🔎 Checking installed files against %s...\n", pm.lockPath)
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	if len(lock) == 0 {
+		fmt.Fprintf(pm.out, "No dependencies installed (%s is empty or missing)\n", pm.lockPath)
+		return nil
+	}
+
+	names := make([]string, 0, len(lock))
+	for name := range lock {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hashes := pm.loadHashCache()
+	failures := make(map[string]error)
+	checked, unchecked := 0, 0
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		if dep, exists := deps[name]; exists && pm.skipUnsupported(name, dep) {
+			continue
+		}
+		lockDep := lock[name]
+		if len(lockDep.Installed) == 0 {
+			fmt.Fprintf(pm.progress, "⚠️  %s: no checksums of its files are recorded, reinstall it to verify them\n", name)
+			unchecked++
+			continue
+		}
+		err := pm.verifyInstalled(hashes, lockDep)
+		if err != nil {
+			fmt.Fprintf(pm.progress, "❌ %s: %v\n", name, err)
+			failures[name] = err
+			continue
+		}
+		fmt.Fprintf(pm.progress, "✓ %s: %s\n", name, lockDep.Path)
+		checked++
+	}
+	err = pm.saveHashCache(hashes)
+	if err != nil {
+		fmt.Fprintf(pm.progress, "Warning: failed to save %s: %v\n", pm.hashCachePath(), err)
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted")
+	}
+	if len(failures) > 0 {
+		printFailureSummary(pm.out, "verify", failures, len(lock))
+		return failedDependenciesError("verify", failures, len(lock))
+	}
+	if unchecked > 0 {
+		fmt.Fprintf(pm.out, "✅ %d dependencies match %s, %d could not be checked\n", checked, pm.lockPath, unchecked)
+		return nil
+	}
+	fmt.Fprintf(pm.out, "✅ %d dependencies match %s\n", checked, pm.lockPath)
+	return nil
+}
+func (pm *Manager) verifyInstalled(hashes hashCache, lockDep LockDependency) error {
+	paths := make([]string, 0, len(lockDep.Installed))
+	for installedPath := range lockDep.Installed {
+		paths = append(paths, installedPath)
+	}
+	sort.Strings(paths)
+
+	var problems []string
+	modified := false
+	for _, installedPath := range paths {
+		actual, err := pathSHA256(pm.targetPath(filepath.FromSlash(installedPath)), hashes)
+		switch {
+		case os.IsNotExist(err):
+			problems = append(problems, installedPath+" is missing")
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("failed to hash %s: %v", installedPath, err))
+		case actual != lockDep.Installed[installedPath]:
+			problems = append(problems, fmt.Sprintf("%s was modified (sha256 %s, locked %s)", installedPath, ShortHash(actual), ShortHash(lockDep.Installed[installedPath])))
+			modified = true
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if modified {
+		return kindErrorf(ErrChecksumMismatch, "%s", strings.Join(problems, "; "))
+	}
+	return fmt.Errorf("%s", strings.Join(problems, "; "))
+}
+func describeResolved(resolved *ResolvedDependency) string {
+	switch {
+	case resolved.Type == "repository":
+		return "commit " + ShortHash(resolved.Version)
+	case len(resolved.Parts) > 0:
+		return fmt.Sprintf("%s, %d assets", resolved.Version, len(resolved.Parts))
+	case resolved.LocalPath != "":
+		return "local " + resolved.LocalPath
+	case resolved.Type == "file" && resolved.Version == "":
+		return resolved.Asset.Name + " is reachable, its content is hashed on install"
+	case resolved.Asset != nil && resolved.Asset.Digest != "":
+		return fmt.Sprintf("%s, %s (checksum published)", ShortHash(resolved.Version), resolved.Asset.Name)
+	case resolved.Asset != nil:
+		return fmt.Sprintf("%s, %s", ShortHash(resolved.Version), resolved.Asset.Name)
+	}
+	return ShortHash(resolved.Version)
+}
+func (pm *Manager) Diff(ctx context.Context) error {
+	fmt.Fprintf(pm.out, "🔍 Comparing %s with the current resolution...\n", pm.lockPath)
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	pm.probeLock = lock
+	defer func() { pm.probeLock = nil }()
+	_, err = pm.expandVersions(ctx, deps, lock, true)
+	if err != nil {
+		return err
+	}
+	err = pm.requireGit(deps)
+	if err != nil {
+		return err
+	}
+
+	resolvedLock := make(LockFile)
+	failures := make(map[string]error)
+	for _, name := range sortedDependencyNames(deps) {
+		dep := deps[name]
+		if ctx.Err() != nil {
+			break
+		}
+		if pm.skipUnsupported(name, dep) {
+			if oldLock, exists := lock[name]; exists {
+				resolvedLock[name] = oldLock
+			}
+			continue
+		}
+		oldLock, exists := lock[name]
+		depCtx, cancel := pm.dependencyContext(ctx, name)
+		resolved, err := pm.resolveDependency(depCtx, name, pinToLock(dep, oldLock, exists))
+		if err != nil {
+			err = pm.contextError(depCtx, err)
+		}
+		cancel()
+		if err != nil {
+			fmt.Fprintf(pm.out, "❌ Resolution error for %s: %v\n", name, err)
+			failures[name] = err
+			if exists {
+				resolvedLock[name] = oldLock
+			}
+			continue
+		}
+		resolvedLock[name] = resolved.lockDependency()
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted")
+	}
+
+	printLockDiff(pm.out, lock, resolvedLock)
+	if len(failures) > 0 {
+		printFailureSummary(pm.out, "resolve", failures, len(deps))
+		return failedDependenciesError("resolve", failures, len(deps))
+	}
+	return nil
+}
+func printLockDiff(w io.Writer, lock, resolvedLock LockFile) {
+	names := make([]string, 0, len(lock)+len(resolvedLock))
+	for name := range lock {
+		names = append(names, name)
+	}
+	for name := range resolvedLock {
+		if _, exists := lock[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "\n──────── Diff ────────")
+	unchanged := 0
+	for _, name := range names {
+		old, locked := lock[name]
+		current, configured := resolvedLock[name]
+		switch {
+		case !configured:
+			fmt.Fprintf(w, "  - %s  %s (no longer in the config)\n", name, ShortHash(old.Version))
+		case !locked:
+			fmt.Fprintf(w, "  + %s  %s (not in the lock)\n", name, ShortHash(current.Version))
+		default:
+			changes := lockChanges(old, current)
+			versionChanged := !sameVersion(old.Hash, current.Hash)
+			if !versionChanged && len(changes) == 0 {
+				unchanged++
+				continue
+			}
+			if !versionChanged {
+				fmt.Fprintf(w, "  ~ %s  %s\n", name, ShortHash(current.Version))
+			} else {
+				fmt.Fprintf(w, "  ~ %s  %s -> %s\n", name, ShortHash(old.Version), ShortHash(current.Version))
+			}
+			for _, change := range changes {
+				fmt.Fprintf(w, "      %s\n", change)
+			}
+		}
+	}
+	if unchanged == len(names) {
+		fmt.Fprintln(w, "  No changes")
+	} else if unchanged > 0 {
+		fmt.Fprintf(w, "  (%d unchanged)\n", unchanged)
+	}
+}
+func lockChanges(old, current LockDependency) []string {
+	var changes []string
+	field := func(label, before, after string) {
+		if before != after {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", label, valueOrNone(before), valueOrNone(after)))
+		}
+	}
+	field("source", old.Source, current.Source)
+	field("type", old.Type, current.Type)
+	field("path", old.Path, current.Path)
+	field("ref", old.Ref, current.Ref)
+	field("asset", old.Asset, current.Asset)
+	field("url", old.URL, current.URL)
+	field("assets", strings.Join(lockAssetNames(old.Assets), ", "), strings.Join(lockAssetNames(current.Assets), ", "))
+	return changes
+}
+func lockAssetNames(assets []LockAsset) []string {
+	names := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		names = append(names, asset.Name)
+	}
+	return names
+}
+func valueOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+func (pm *Manager) Vendor(ctx context.Context) error {
+	fmt.Fprintln(pm.progress, "📦 Vendoring dependencies...")
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	_, err = pm.expandVersions(ctx, deps, lock, false)
+	if err != nil {
+		return err
+	}
+
+	manifest := make(VendorManifest)
+	newLock := make(LockFile)
+	failures := make(map[string]error)
+	for _, name := range sortedDependencyNames(deps) {
+		dep := deps[name]
+		if ctx.Err() != nil {
+			break
+		}
+		if pm.skipUnsupported(name, dep) {
+			if oldLock, exists := lock[name]; exists {
+				newLock[name] = oldLock
+			}
+			continue
+		}
+		// install --offline restores the locked versions, so those are
+		// vendored as locked; only new dependencies are resolved.
+		oldLock, exists := lock[name]
+		_, isLocal := localSourcePath(dep.Source)
+		locked := exists && oldLock.Source == dep.Source && !(isLocal && dep.Type != "repository")
+		depCtx, cancel := pm.dependencyContext(ctx, name)
+		var resolved *ResolvedDependency
+		if locked {
+			resolved, err = pm.resolveFromLock(name, dep, oldLock)
+		} else {
+			resolved, err = pm.resolveDependency(depCtx, name, pinToLock(dep, oldLock, exists))
+		}
+		if err == nil {
+			err = pm.vendorDependency(depCtx, resolved, manifest)
+		}
+		if err != nil {
+			err = pm.contextError(depCtx, err)
+		}
+		cancel()
+		if err != nil {
+			fmt.Fprintf(pm.progress, "❌ Vendoring error for %s: %v\n", name, err)
+			failures[name] = err
+			if exists {
+				newLock[name] = oldLock
+			}
+			continue
+		}
+
+		if locked {
+			if oldLock.SHA256 == "" {
+				oldLock.SHA256 = resolved.expectedSHA256()
+			}
+			newLock[name] = oldLock
+			continue
+		}
+		newLock[name] = resolved.carryOverLock(oldLock)
+	}
+	if !pm.noLock {
+		pm.applyLockPruning(lock, newLock, deps)
+		err = pm.saveLockFile(newLock)
+		if err != nil {
+			return fmt.Errorf("failed to save %s: %v", pm.lockPath, err)
+		}
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(pm.targetPath(pm.vendorPath), data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %v", pm.vendorPath, err)
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted")
+	}
+	if len(failures) > 0 {
+		printFailureSummary(pm.out, "vendor", failures, len(deps))
+		return failedDependenciesError("vendor", failures, len(deps))
+	}
+
+	fmt.Fprintf(pm.progress, "✅ Vendored %d dependencies, manifest written to %s\n", len(manifest), pm.vendorPath)
+	return nil
+}
+func (pm *Manager) vendorDependency(ctx context.Context, resolved *ResolvedDependency, manifest VendorManifest) error {
+	if resolved.Type == "repository" || resolved.LocalPath != "" {
+		fmt.Fprintf(pm.progress, "⏭️  Not vendoring %s: only release assets and source archives are vendored\n", resolved.Name)
+		return nil
+	}
+	if len(resolved.Parts) > 0 {
+		return fmt.Errorf("asset_name matches %d assets, vendoring supports one file per dependency", len(resolved.Parts))
+	}
+
+	fileName := fmt.Sprintf("%s-%s.%s", resolved.Repo, strings.ReplaceAll(resolved.Version, "/", "-"), resolved.SourceFormat)
+	if resolved.Asset != nil {
+		fileName = resolved.Asset.Name
+	}
+	configName := strings.TrimSuffix(filepath.Base(pm.configPath), filepath.Ext(pm.configPath))
+	vendorDir := filepath.Join(filepath.Dir(pm.vendorPath), configName, resolved.Name)
+
+	// Download into a temp directory first so a failed download keeps the
+	// previously vendored copy.
+	tmpDir, err := pm.createTempDir()
+	if err != nil {
+		return fmt.Errorf("failed to create tmp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpPath := filepath.Join(tmpDir, fileName)
+	err = pm.downloadResolved(ctx, resolved, tmpPath, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	hash, err := fileSHA256(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.RemoveAll(pm.targetPath(vendorDir))
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(pm.targetPath(vendorDir), 0755)
+	if err != nil {
+		return err
+	}
+	vendoredFile := filepath.Join(vendorDir, fileName)
+	err = movePath(tmpPath, pm.targetPath(vendoredFile))
+	if err != nil {
+		return err
+	}
+
+	manifest[resolved.Name] = VendorEntry{
+		Version: resolved.Version,
+		URL:     resolved.DownloadURL,
+		File:    filepath.ToSlash(vendoredFile),
+		SHA256:  hash,
+	}
+	fmt.Fprintf(pm.progress, "✓ Vendored: %s (version: %s) -> %s\n", resolved.Name, ShortHash(resolved.Version), vendoredFile)
+	return nil
+}
+func (pm *Manager) loadVendorManifest() (VendorManifest, error) {
+	data, err := os.ReadFile(pm.targetPath(pm.vendorPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest VendorManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+func (pm *Manager) Export(ctx context.Context) error {
+	fmt.Fprintln(pm.out, "📤 Resolving dependencies for export...")
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	pm.probeLock = lock
+	defer func() { pm.probeLock = nil }()
+	_, err = pm.expandVersions(ctx, deps, lock, false)
+	if err != nil {
+		return err
+	}
+	err = pm.requireGit(deps)
+	if err != nil {
+		return err
+	}
+
+	exportPath := pm.exportPath
+	if exportPath == "" {
+		exportPath = generateExportFileName(pm.configPath)
+	}
+
+	export := make(ExportFile)
+	failures := make(map[string]error)
+	for _, name := range sortedDependencyNames(deps) {
+		dep := deps[name]
+		if ctx.Err() != nil {
+			break
+		}
+		if pm.skipUnsupported(name, dep) {
+			continue
+		}
+		// The export lists what install would fetch, so pins and checksums
+		// come from the lock.
+		oldLock, exists := lock[name]
+		depCtx, cancel := pm.dependencyContext(ctx, name)
+		resolved, err := pm.resolveDependency(depCtx, name, pinToLock(dep, oldLock, exists))
+		if err != nil {
+			err = pm.contextError(depCtx, err)
+		}
+		cancel()
+		if err != nil {
+			fmt.Fprintf(pm.out, "❌ Resolution error for %s: %v\n", name, err)
+			failures[name] = err
+			continue
+		}
+		if exists {
+			resolved.useLockedChecksums(oldLock)
+		}
+
+		export[name] = pm.exportEntry(resolved)
+		fmt.Fprintf(pm.out, "✓ Resolved: %s (version: %s)\n", name, ShortHash(resolved.Version))
+	}
+
+	// A partial export would look complete to whatever consumes it, so the
+	// file is only written once every dependency resolved.
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted, %s was not written", exportPath)
+	}
+	if len(failures) > 0 {
+		printFailureSummary(pm.out, "resolve", failures, len(deps))
+		return fmt.Errorf("%v; %s was not written", failedDependenciesError("resolve", failures, len(deps)), exportPath)
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(pm.targetPath(exportPath), data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %v", exportPath, err)
+	}
+
+	fmt.Fprintf(pm.out, "✅ Export written to %s (nothing was downloaded)\n", exportPath)
+	return nil
+}
+func (pm *Manager) exportEntry(resolved *ResolvedDependency) ExportEntry {
+	if len(resolved.Parts) > 0 {
+		entry := ExportEntry{
+			Type:    resolved.Type,
+			Source:  resolved.Dependency.Source,
+			Version: resolved.Version,
+			Path:    resolved.ExpandedPath,
+		}
+		for _, part := range resolved.Parts {
+			entry.Assets = append(entry.Assets, pm.exportEntry(part))
+		}
+		return entry
+	}
+
+	entry := ExportEntry{
+		Type:    resolved.Type,
+		Source:  resolved.Dependency.Source,
+		Version: resolved.Version,
+		Path:    resolved.ExpandedPath,
+	}
+
+	switch {
+	case resolved.LocalPath != "":
+		if resolved.Asset != nil {
+			entry.SHA256 = resolved.Version
+		}
+	case resolved.Type == "repository":
+		entry.URLs = []string{resolved.Dependency.Source}
+	default:
+		entry.URLs = []string{resolved.DownloadURL}
+		if resolved.Asset != nil {
+			entry.Asset = resolved.Asset.Name
+			entry.Size = resolved.Asset.Size
+			if browserURL := resolved.Asset.BrowserDownloadURL; browserURL != "" && browserURL != resolved.DownloadURL {
+				entry.URLs = append(entry.URLs, browserURL)
+			}
+		}
+		fileName := path.Base(resolved.DownloadURL)
+		if resolved.Asset != nil {
+			fileName = resolved.Asset.Name
+		}
+		for _, mirror := range resolved.Dependency.Mirrors {
+			entry.URLs = append(entry.URLs, expandMirrorURL(mirror, resolved.Version, fileName))
+		}
+		entry.SHA256 = resolved.expectedSHA256()
+	}
+
+	return entry
+}
+func (pm *Manager) Graph(format string) error {
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	names := make([]string, 0, len(lock))
+	for name := range lock {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var document interface{}
+	switch format {
+	case "", "cyclonedx":
+		document = pm.cycloneDXBOM(lock, names)
+	case "spdx":
+		document = pm.spdxDocument(lock, names)
+	default:
+		return fmt.Errorf("unknown format %q, expected cyclonedx or spdx", format)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(document)
+	if err != nil {
+		return err
+	}
+	// Without -o the document is the only thing written to stdout, so it
+	// can be piped straight into other tools.
+	if pm.exportPath == "" {
+		_, err = pm.out.Write(buf.Bytes())
+		return err
+	}
+	err = writeFileAtomic(pm.targetPath(pm.exportPath), buf.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %v", pm.exportPath, err)
+	}
+	fmt.Fprintf(pm.out, "✅ Inventory of %d dependencies written to %s\n", len(names), pm.exportPath)
+	return nil
+}
+func lockSHA256(lockDep LockDependency) string {
+	if lockDep.SHA256 != "" {
+		return lockDep.SHA256
+	}
+	// Raw files and local assets are versioned by their content hash.
+	if _, isLocal := localSourcePath(lockDep.Source); (lockDep.Type == "file" || isLocal) && isSHA256Hex(lockDep.Version) {
+		return lockDep.Version
+	}
+	return ""
+}
+func isSHA256Hex(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+func (pm *Manager) packageURL(lockDep LockDependency) string {
+	if _, isLocal := localSourcePath(lockDep.Source); isLocal || lockDep.Type == "file" {
+		return ""
+	}
+	owner, repo, err := pm.extractRepoInfo(lockDep.Source)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("pkg:github/%s/%s@%s", strings.ToLower(owner), strings.ToLower(repo), url.PathEscape(lockDep.Version))
+}
+func (pm *Manager) cycloneDXBOM(lock LockFile, names []string) CycloneDXBOM {
+	bom := CycloneDXBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: CycloneDXMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: CycloneDXTools{
+				Components: []CycloneDXComponent{{Type: "application", Name: "fracture", Version: pm.version}},
+			},
+			Component: CycloneDXComponent{Type: "application", Name: filepath.Base(pm.workDir)},
+		},
+		Components: []CycloneDXComponent{},
+	}
+	for _, name := range names {
+		lockDep := lock[name]
+		component := CycloneDXComponent{
+			Type:    "application",
+			BOMRef:  name,
+			Name:    name,
+			Version: lockDep.Version,
+			PURL:    pm.packageURL(lockDep),
+		}
+		switch lockDep.Type {
+		case "source", "repository":
+			component.Type = "library"
+		case "file":
+			component.Type = "file"
+		}
+		if _, isLocal := localSourcePath(lockDep.Source); !isLocal && lockDep.Type != "file" {
+			component.ExternalReferences = append(component.ExternalReferences, CycloneDXReference{Type: "vcs", URL: lockDep.Source})
+		}
+		if lockDep.URL != "" {
+			component.ExternalReferences = append(component.ExternalReferences, CycloneDXReference{Type: "distribution", URL: lockDep.URL})
+		}
+		if hash := lockSHA256(lockDep); hash != "" {
+			component.Hashes = []CycloneDXHash{{Alg: "SHA-256", Content: hash}}
+		}
+		properties := [][2]string{
+			{"fracture:type", lockDep.Type},
+			{"fracture:path", lockDep.Path},
+			{"fracture:asset", lockDep.Asset},
+			{"fracture:ref", lockDep.Ref},
+			{"fracture:commit", lockDep.Commit},
+			{"fracture:published_at", lockDep.PublishedAt},
+			{"fracture:package_version", lockDep.PackageVersion},
+		}
+		if lockDep.Type == "repository" && lockDep.Commit == "" {
+			properties = append(properties, [2]string{"fracture:commit", lockDep.Hash})
+		}
+		for _, property := range properties {
+			if property[1] != "" {
+				component.Properties = append(component.Properties, CycloneDXProperty{Name: property[0], Value: property[1]})
+			}
+		}
+		for _, asset := range lockDep.Assets {
+			part := CycloneDXComponent{
+				Type:               "file",
+				BOMRef:             name + "/" + asset.Name,
+				Name:               asset.Name,
+				Version:            lockDep.Version,
+				ExternalReferences: []CycloneDXReference{{Type: "distribution", URL: asset.URL}},
+			}
+			if asset.SHA256 != "" {
+				part.Hashes = []CycloneDXHash{{Alg: "SHA-256", Content: asset.SHA256}}
+			}
+			component.Components = append(component.Components, part)
+		}
+		bom.Components = append(bom.Components, component)
+	}
+	return bom
+}
+func (pm *Manager) spdxDocument(lock LockFile, names []string) SPDXDocument {
+	project := filepath.Base(pm.workDir)
+	created := time.Now().UTC().Format(time.RFC3339)
+	// The namespace only has to be unique per document, so it is derived
+	// from the project, the lock contents and the creation time.
+	digest := sha256.New()
+	fmt.Fprintf(digest, "%s\n%s\n", project, created)
+	for _, name := range names {
+		fmt.Fprintf(digest, "%s %s\n", name, lock[name].Hash)
+	}
+
+	document := SPDXDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              project,
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/fracture-%s-%s", url.PathEscape(project), hex.EncodeToString(digest.Sum(nil))[:16]),
+		CreationInfo: SPDXCreationInfo{
+			Created:  created,
+			Creators: []string{"Tool: fracture-" + pm.version},
+		},
+		Packages:      []SPDXPackage{},
+		Relationships: []SPDXRelationship{},
+	}
+	for _, name := range names {
+		lockDep := lock[name]
+		spdxID := spdxIdentifier(name)
+		downloadLocation := lockDep.URL
+		if lockDep.Type == "repository" {
+			downloadLocation = "git+" + lockDep.Source + "@" + lockDep.Hash
+		}
+		pkg := spdxPackage(spdxID, name, lockDep.Version, downloadLocation, lockSHA256(lockDep))
+		if purl := pm.packageURL(lockDep); purl != "" {
+			pkg.ExternalRefs = []SPDXExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: purl}}
+		}
+		document.Packages = append(document.Packages, pkg)
+		document.Relationships = append(document.Relationships, SPDXRelationship{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: spdxID})
+		for _, asset := range lockDep.Assets {
+			assetID := spdxIdentifier(name + "-" + asset.Name)
+			document.Packages = append(document.Packages, spdxPackage(assetID, asset.Name, lockDep.Version, asset.URL, asset.SHA256))
+			document.Relationships = append(document.Relationships, SPDXRelationship{SPDXElementID: spdxID, RelationshipType: "CONTAINS", RelatedSPDXElement: assetID})
+		}
+	}
+	return document
+}
+func spdxPackage(spdxID, name, version, downloadLocation, sha string) SPDXPackage {
+	if downloadLocation == "" {
+		downloadLocation = "NOASSERTION"
+	}
+	pkg := SPDXPackage{
+		SPDXID:           spdxID,
+		Name:             name,
+		VersionInfo:      version,
+		DownloadLocation: downloadLocation,
+		LicenseConcluded: "NOASSERTION",
+		LicenseDeclared:  "NOASSERTION",
+		CopyrightText:    "NOASSERTION",
+	}
+	if sha != "" {
+		pkg.Checksums = []SPDXChecksum{{Algorithm: "SHA256", ChecksumValue: sha}}
+	}
+	return pkg
+}
+func spdxIdentifier(name string) string {
+	// SPDX identifiers may only contain letters, digits, "." and "-".
+	return "SPDXRef-Package-" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+}
+func (pm *Manager) List(sizes bool) error {
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	if len(lock) == 0 {
+		fmt.Fprintf(pm.out, "No dependencies installed (%s is empty or missing)\n", pm.lockPath)
+		return nil
+	}
+
+	names := make([]string, 0, len(lock))
+	nameWidth, versionWidth := 0, 0
+	for name, lockDep := range lock {
+		names = append(names, name)
+		if len(name) > nameWidth {
+			nameWidth = len(name)
+		}
+		if len(ShortHash(lockDep.Version)) > versionWidth {
+			versionWidth = len(ShortHash(lockDep.Version))
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(pm.out, "📦 Installed dependencies (%s):\n", pm.lockPath)
+	var total int64
+	for _, name := range names {
+		lockDep := lock[name]
+		line := fmt.Sprintf("  %-*s  %-*s  %s", nameWidth, name, versionWidth, ShortHash(lockDep.Version), lockDep.Path)
+		if sizes {
+			// Sizes are measured on disk, so files added or removed after
+			// install are counted too.
+			size, err := pathSize(pm.targetPath(lockDep.Path))
+			if os.IsNotExist(err) {
+				line += "  (missing)"
+			} else if err != nil {
+				line += fmt.Sprintf("  (error: %v)", err)
+			} else {
+				total += size
+				line += "  " + FormatSize(size)
+			}
+		}
+		fmt.Fprintln(pm.out, line)
+	}
+	if sizes {
+		fmt.Fprintf(pm.out, "\nTotal: %s\n", FormatSize(total))
+	}
+	return nil
+}
+func isPinned(dep Dependency) bool {
+	// The versions of one entry of a "versions" dependency come from the
+	// release list, not from the config.
+	return dep.Pinned || (dep.Version != "" && dep.versionOf == "")
+}
+func pinDescription(dep Dependency) string {
+	if dep.Version != "" {
+		return "pinned to " + dep.Version
+	}
+	return "pinned"
+}
+func pinToLock(dep Dependency, lockDep LockDependency, locked bool) Dependency {
+	// pinned: true without a version keeps the locked release on install.
+	if dep.Pinned && dep.Version == "" && dep.Ref == "" && locked && (lockDep.Type == "binary" || lockDep.Type == "source") {
+		if _, isLocal := localSourcePath(dep.Source); !isLocal {
+			dep.Version = lockDep.Version
+		}
+	}
+	return dep
+}
+func (pm *Manager) expandVersions(ctx context.Context, deps DepsFile, lock LockFile, refresh bool) (map[string][]string, error) {
+	// A dependency with "versions: N" is replaced by one entry per release,
+	// named <name>@<tag>, so each is installed, locked and pruned on its own.
+	expanded := make(map[string][]string)
+	for _, name := range sortedDependencyNames(deps) {
+		dep := deps[name]
+		if dep.Versions == 0 {
+			continue
+		}
+		tags, err := pm.versionTags(ctx, name, dep, lock, refresh)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %s: %v", name, err)
+		}
+		delete(deps, name)
+		for _, tag := range tags {
+			versionDep := dep
+			versionDep.Versions = 0
+			versionDep.Version = tag
+			versionDep.versionOf = name
+			deps[name+"@"+tag] = versionDep
+			expanded[name] = append(expanded[name], name+"@"+tag)
+		}
+	}
+	if len(expanded) == 0 {
+		return expanded, nil
+	}
+	// Requiring a versions dependency means requiring all of its releases.
+	for name, dep := range deps {
+		var requires []string
+		for _, required := range dep.Requires {
+			if versionNames, exists := expanded[required]; exists {
+				requires = append(requires, versionNames...)
+			} else {
+				requires = append(requires, required)
+			}
+		}
+		dep.Requires = requires
+		deps[name] = dep
+	}
+	return expanded, nil
+}
+func (pm *Manager) versionTags(ctx context.Context, name string, dep Dependency, lock LockFile, refresh bool) ([]string, error) {
+	depType := pm.dependencyType(name, dep)
+	if dep.Versions < 0 {
+		return nil, fmt.Errorf("versions must be a positive number")
+	}
+	if _, isLocal := localSourcePath(dep.Source); isLocal || (depType != "binary" && depType != "source") {
+		return nil, fmt.Errorf("versions is only supported for binary and source dependencies from GitHub releases")
+	}
+	if dep.Version != "" || dep.Ref != "" {
+		return nil, fmt.Errorf("versions cannot be combined with version or ref")
+	}
+	if dep.KeepVersions > 0 {
+		return nil, fmt.Errorf("versions cannot be combined with keep_versions, releases that drop out are removed with --prune-files")
+	}
+	if !strings.Contains(dep.Path, "@VERSION") {
+		return nil, fmt.Errorf("versions requires @VERSION in path so the releases are installed side by side")
+	}
+
+	// Installs reuse the locked releases while there are as many as asked
+	// for; update, lock and diff look at the release list again unless the
+	// dependency is pinned.
+	var locked []string
+	for lockName, lockDep := range lock {
+		if tag, found := strings.CutPrefix(lockName, name+"@"); found && tag == lockDep.Version {
+			locked = append(locked, tag)
+		}
+	}
+	if len(locked) == dep.Versions && (!refresh || dep.Pinned) {
+		sort.Strings(locked)
+		return locked, nil
+	}
+
+	owner, repo, err := pm.extractRepoInfo(dep.Source)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := pm.listReleases(ctx, owner, repo, dep.Private)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []*GitHubRelease
+	for i := range releases {
+		release := &releases[i]
+		if release.Draft || (release.Prerelease && !dep.Prerelease) || !strings.HasPrefix(release.TagName, dep.TagPrefix) {
+			continue
+		}
+		candidates = append(candidates, release)
+	}
+	// The release list is newest first; monorepo tags and resolve: semver
+	// are ordered by version instead, as for a single release.
+	if dep.TagPrefix != "" || dep.Resolve == "semver" {
+		versions := make(map[string]semver)
+		var parsed []*GitHubRelease
+		for _, release := range candidates {
+			version, err := parseSemver(strings.TrimPrefix(release.TagName, dep.TagPrefix))
+			if err != nil {
+				continue
+			}
+			versions[release.TagName] = version
+			parsed = append(parsed, release)
+		}
+		sort.SliceStable(parsed, func(i, j int) bool {
+			return compareSemver(versions[parsed[i].TagName], versions[parsed[j].TagName]) > 0
+		})
+		candidates = parsed
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no releases found in %s/%s", owner, repo)
+	}
+
+	var tags []string
+	for i := 0; i < len(candidates) && i < dep.Versions; i++ {
+		tags = append(tags, candidates[i].TagName)
+	}
+	if len(tags) < dep.Versions {
+		fmt.Fprintf(pm.progress, "⚠️  %s: only %d of %d requested releases exist\n", name, len(tags), dep.Versions)
+	}
+	return tags, nil
+}
+func (pm *Manager) Pin(dependencyName string) error {
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	dep, exists := deps[dependencyName]
+	if !exists {
+		return fmt.Errorf("dependency %s not found", dependencyName)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	lockDep, exists := lock[dependencyName]
+	if !exists {
+		return fmt.Errorf("%s has no entry in %s; run 'fracture install' first", dependencyName, pm.lockPath)
+	}
+	if _, isLocal := localSourcePath(dep.Source); isLocal || (lockDep.Type != "binary" && lockDep.Type != "source") {
+		return fmt.Errorf("only binary and source dependencies from GitHub releases can be pinned, %s is a %s dependency", dependencyName, lockDep.Type)
+	}
+	if dep.Ref != "" {
+		return fmt.Errorf("%s is already fixed by ref %s", dependencyName, dep.Ref)
+	}
+
+	err = pm.setConfigVersion(dependencyName, lockDep.Version)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(pm.progress, "📌 Pinned %s to %s in %s\n", dependencyName, lockDep.Version, pm.configPath)
+	return nil
+}
+func (pm *Manager) Unpin(dependencyName string) error {
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	dep, exists := deps[dependencyName]
+	if !exists {
+		return fmt.Errorf("dependency %s not found", dependencyName)
+	}
+	if dep.Version == "" {
+		fmt.Fprintf(pm.progress, "%s is not pinned\n", dependencyName)
+		return nil
+	}
+
+	err = pm.setConfigVersion(dependencyName, "")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(pm.progress, "📌 Unpinned %s (was %s), the next update moves it to the latest release\n", dependencyName, dep.Version)
+	return nil
+}
+func (pm *Manager) setConfigVersion(dependencyName, version string) error {
+	// The config is edited in place rather than re-encoded, so comments,
+	// key order and formatting survive.
+	configPath := pm.targetPath(pm.configPath)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	standardized := standardizeJSON(data)
+
+	start := bytes.IndexByte(standardized, '{')
+	if start < 0 {
+		return fmt.Errorf("%s is not a JSON object", pm.configPath)
+	}
+	trimmed := bytes.TrimLeft(standardized, " \t\r\n")
+	if isStructuredConfig(trimmed) {
+		section, found, err := findJSONMember(standardized, start, "dependencies")
+		if err != nil || !found {
+			return fmt.Errorf("cannot find dependencies in %s", pm.configPath)
+		}
+		start = section.valueStart
+	}
+	depMember, found, err := findJSONMember(standardized, start, dependencyName)
+	if err != nil || !found || standardized[depMember.valueStart] != '{' {
+		return fmt.Errorf("cannot find dependency %s in %s", dependencyName, pm.configPath)
+	}
+	members, err := jsonMembers(standardized, depMember.valueStart)
+	if err != nil || len(members) == 0 {
+		return fmt.Errorf("cannot edit dependency %s in %s", dependencyName, pm.configPath)
+	}
+
+	quoted, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+	var edited []byte
+	index := -1
+	for i, member := range members {
+		if member.name == "version" {
+			index = i
+		}
+	}
+	switch {
+	case index >= 0 && version != "":
+		member := members[index]
+		edited = spliceBytes(data, member.valueStart, member.valueEnd, quoted)
+	case index == 0 && len(members) > 1:
+		edited = spliceBytes(data, members[0].keyStart, members[1].keyStart, nil)
+	case index == 0:
+		edited = spliceBytes(data, members[0].keyStart, members[0].valueEnd, nil)
+	case index > 0:
+		edited = spliceBytes(data, members[index-1].valueEnd, members[index].valueEnd, nil)
+	case version != "":
+		// The new key goes after the last one, on its own line with the
+		// same indentation when the object spans several lines.
+		last := members[len(members)-1]
+		lineStart := bytes.LastIndexByte(data[:last.keyStart], '\n')
+		separator := ", "
+		if lineStart > depMember.valueStart {
+			separator = ",\n" + string(data[lineStart+1:last.keyStart])
+		}
+		edited = spliceBytes(data, last.valueEnd, last.valueEnd, []byte(separator+`"version": `+string(quoted)))
+	default:
+		return nil
+	}
+
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(configPath, edited, info.Mode().Perm())
+}
+func findJSONMember(data []byte, start int, name string) (jsonMember, bool, error) {
+	members, err := jsonMembers(data, start)
+	if err != nil {
+		return jsonMember{}, false, err
+	}
+	for _, member := range members {
+		if member.name == name {
+			return member, true, nil
+		}
+	}
+	return jsonMember{}, false, nil
+}
+func jsonMembers(data []byte, start int) ([]jsonMember, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data[start:]))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("expected an object at offset %d", start)
+	}
+
+	var members []jsonMember
+	for decoder.More() {
+		previousEnd := start + int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := token.(string)
+		var value json.RawMessage
+		err = decoder.Decode(&value)
+		if err != nil {
+			return nil, err
+		}
+		valueEnd := start + int(decoder.InputOffset())
+		members = append(members, jsonMember{
+			name:       name,
+			keyStart:   previousEnd + bytes.IndexByte(data[previousEnd:], '"'),
+			valueStart: valueEnd - len(value),
+			valueEnd:   valueEnd,
+		})
+	}
+	return members, nil
+}
+func spliceBytes(data []byte, start, end int, insert []byte) []byte {
+	result := make([]byte, 0, len(data)-(end-start)+len(insert))
+	result = append(result, data[:start]...)
+	result = append(result, insert...)
+	return append(result, data[end:]...)
+}
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f TiB", value)
+}
+
+type doctorCheck struct {
+	out      io.Writer
+	failed   int
+	warnings int
+}
+
+func (c *doctorCheck) pass(name, format string, args ...interface{}) {
+	fmt.Fprintf(c.out, "✅ %s: %s\n", name, fmt.Sprintf(format, args...))
+}
+func (c *doctorCheck) warn(name, hint, format string, args ...interface{}) {
+	c.warnings++
+	fmt.Fprintf(c.out, "⚠️  %s: %s\n", name, fmt.Sprintf(format, args...))
+	fmt.Fprintf(c.out, "   → %s\n", hint)
+}
+func (c *doctorCheck) fail(name, hint, format string, args ...interface{}) {
+	c.failed++
+	fmt.Fprintf(c.out, "❌ %s: %s\n", name, fmt.Sprintf(format, args...))
+	fmt.Fprintf(c.out, "   → %s\n", hint)
+}
+func (pm *Manager) Doctor(ctx context.Context) error {
+	fmt.Fprintln(pm.out, "🩺 Checking fracture environment...")
+	check := &doctorCheck{out: pm.out}
+
+	deps, configErr := pm.loadDepsFile()
+	pm.checkGit(ctx, check, deps)
+	pm.checkConfig(check, deps, configErr)
+	pm.checkCacheDir(check)
+	if pm.offline {
+		check.warn("github", "run without --offline to test connectivity", "skipped in offline mode")
+	} else {
+		pm.checkGitHub(ctx, check, deps)
+	}
+
+	fmt.Fprintln(pm.out, "")
+	if check.failed > 0 {
+		return fmt.Errorf("%d check(s) failed, %d warning(s)", check.failed, check.warnings)
+	}
+	fmt.Fprintf(pm.out, "✅ All checks passed (%d warning(s))\n", check.warnings)
+	return nil
+}
+func (pm *Manager) checkGit(ctx context.Context, check *doctorCheck, deps DepsFile) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		if needed := pm.gitDependencies(deps); len(needed) > 0 {
+			check.fail("git", "install git and make sure it is on PATH", "not found on PATH, but %s need it", strings.Join(needed, ", "))
+		} else {
+			check.warn("git", "install git to use repository dependencies", "not found on PATH")
+		}
+		return
+	}
+
+	output, err := exec.CommandContext(ctx, gitPath, "--version").Output()
+	if err != nil {
+		check.fail("git", "check that the git installation works", "%s --version failed: %v", gitPath, err)
+		return
+	}
+	check.pass("git", "%s (%s)", strings.TrimSpace(string(output)), gitPath)
+}
+func (pm *Manager) checkConfig(check *doctorCheck, deps DepsFile, configErr error) {
+	if configErr != nil {
+		check.fail("config", "fix "+pm.configPath+" or pass the right file with -c", "%v", configErr)
+		return
+	}
+
+	problems := 0
+	for _, name := range sortedDependencyNames(deps) {
+		dep := deps[name]
+		depType := pm.dependencyType(name, dep)
+		var problem error
+		if depType != "binary" && depType != "source" && depType != "repository" && depType != "file" {
+			problem = fmt.Errorf("unknown type %q", depType)
+		} else if _, err := fileModeFor(dep, depType); err != nil {
+			problem = err
+		} else if _, isLocal := localSourcePath(dep.Source); !isLocal && depType != "repository" && depType != "file" {
+			_, _, problem = pm.extractRepoInfo(dep.Source)
+		}
+		if problem != nil {
+			check.fail("config", "fix the "+name+" entry in "+pm.configPath, "%s: %v", name, problem)
+			problems++
+		}
+	}
+	if problems == 0 {
+		check.pass("config", "%s is valid (%d dependencies)", pm.configPath, len(deps))
+	}
+}
+func (pm *Manager) checkCacheDir(check *doctorCheck) {
+	if pm.cacheDir == "" {
+		check.warn("cache", "set FRACTURE_CACHE_DIR to enable the download cache", "no cache directory could be determined")
+		return
+	}
+	err := os.MkdirAll(pm.cacheDir, 0755)
+	if err == nil {
+		var probe *os.File
+		probe, err = os.CreateTemp(pm.cacheDir, ".doctor-*")
+		if err == nil {
+			probe.Close()
+			os.Remove(probe.Name())
+		}
+	}
+	if err != nil {
+		check.warn("cache", "make the directory writable or set FRACTURE_CACHE_DIR", "%s is not writable: %v", pm.cacheDir, err)
+		return
+	}
+	check.pass("cache", "%s is writable", pm.cacheDir)
+}
+func (pm *Manager) checkGitHub(ctx context.Context, check *doctorCheck, deps DepsFile) {
+	req, err := pm.newRequest(ctx, "GET", "https://api.github.com/rate_limit")
+	if err != nil {
+		check.fail("github", "check the GitHub API URL", "%v", err)
+		return
+	}
+	req.Header.Set("Accept", mediaTypeGitHubJSON)
+	resp, err := pm.httpClient.Do(req)
+	if err != nil {
+		check.fail("github", "check your network connection, proxy settings (HTTPS_PROXY) and FRACTURE_CA_BUNDLE", "api.github.com is unreachable: %v", err)
+		return
+	}
+	resp.Body.Close()
+	check.pass("github", "api.github.com is reachable (anonymous rate limit remaining: %s)", resp.Header.Get("X-RateLimit-Remaining"))
+
+	hasPrivate := false
+	for _, dep := range deps {
+		if dep.Private {
+			hasPrivate = true
+		}
+	}
+	if pm.githubToken == "" {
+		if hasPrivate {
+			check.fail("token", "set FRACTURE_GITHUB_PAT to a token with access to the private repositories", "none of %s is set, but the config has private dependencies", strings.Join(githubTokenVars, ", "))
+		} else {
+			check.warn("token", "set FRACTURE_GITHUB_PAT to raise the API rate limit", "none of %s is set (only needed for private repositories)", strings.Join(githubTokenVars, ", "))
+		}
+		return
+	}
+
+	req, err = pm.createAuthenticatedRequest(ctx, "GET", "https://api.github.com/user", mediaTypeGitHubJSON)
+	if err != nil {
+		check.fail("token", "check the GitHub API URL", "%v", err)
+		return
+	}
+	resp, err = pm.httpClient.Do(req)
+	if err != nil {
+		check.fail("token", "check your network connection", "failed to verify %s: %v", pm.githubEnv, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode == 401 {
+		check.fail("token", "create a new token and update "+pm.githubEnv, "%s was rejected (expired or revoked)", pm.githubEnv)
+		return
+	}
+	if resp.StatusCode != 200 {
+		check.warn("token", "check the token's permissions", "GitHub returned status %d for /user", resp.StatusCode)
+		return
+	}
+
+	// Classic tokens report their scopes; fine-grained tokens don't send the header.
+	scopes := resp.Header.Get("X-OAuth-Scopes")
+	if scopes == "" {
+		scopes = "not reported (fine-grained token)"
+	}
+	check.pass("token", "%s is valid (scopes: %s, rate limit remaining: %s)", pm.githubEnv, scopes, resp.Header.Get("X-RateLimit-Remaining"))
+}
+func sortedDependencyNames(deps DepsFile) []string {
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+func (pm *Manager) Search(ctx context.Context, source, tag string) error {
+	// Search needs no config: it shows what a repository offers so a
+	// dependency entry can be written against real tag and asset names.
+	if !strings.Contains(source, "github.com/") {
+		source = "github.com/" + source
+	}
+	owner, repo, err := pm.extractRepoInfo(source)
+	if err != nil {
+		return err
+	}
+	// Only the newest releases are shown, so one page is enough.
+	const shown = 10
+	if pm.offline {
+		return fmt.Errorf("cannot query releases for %s/%s in offline mode", owner, repo)
+	}
+	releases, err := pm.listReleasePage(ctx, owner, repo, shown, 1)
+	if err != nil {
+		return err
+	}
+
+	var selected *GitHubRelease
+	if tag != "" {
+		selected, err = pm.getReleaseByTag(ctx, owner, repo, tag, false)
+		if err != nil {
+			return err
+		}
+	} else {
+		selected, err = pm.getLatestRelease(ctx, owner, repo, false)
+		if errors.Is(err, ErrNotFound) && len(releases) > 0 {
+			// Repositories that only publish pre-releases have no latest.
+			selected, err = &releases[0], nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	latest := ""
+	if tag == "" {
+		latest = selected.TagName
+	}
+
+	fmt.Fprintf(pm.out, "🔎 Releases of %s/%s (newest first):\n", owner, repo)
+	if len(releases) == 0 {
+		fmt.Fprintln(pm.out, "  none")
+	}
+	for _, release := range releases {
+		var notes []string
+		if release.TagName == latest {
+			notes = append(notes, "latest")
+		}
+		if release.Prerelease {
+			notes = append(notes, "pre-release")
+		}
+		if release.Draft {
+			notes = append(notes, "draft")
+		}
+		line := fmt.Sprintf("  %-20s %s", release.TagName, strings.SplitN(release.PublishedAt, "T", 2)[0])
+		if len(notes) > 0 {
+			line += " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Fprintln(pm.out, line)
+	}
+	if len(releases) == shown {
+		fmt.Fprintln(pm.out, "  ... older releases are not shown, pass --tag to see one")
+	}
+
+	fmt.Fprintf(pm.out, "\n📦 Assets of %s:\n", selected.TagName)
+	if len(selected.Assets) == 0 {
+		fmt.Fprintln(pm.out, "  none (use type \"source\" for the source archive)")
+	}
+	nameWidth := 0
+	for _, asset := range selected.Assets {
+		if len(asset.Name) > nameWidth {
+			nameWidth = len(asset.Name)
+		}
+	}
+	for _, asset := range selected.Assets {
+		fmt.Fprintf(pm.out, "  %-*s  %s\n", nameWidth, asset.Name, FormatSize(asset.Size))
+	}
+	return nil
+}
+func (pm *Manager) Why(ctx context.Context, dependencyName string) error {
+	deps, err := pm.loadDepsFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.configPath, err)
+	}
+	dep, exists := deps[dependencyName]
+	if !exists {
+		return fmt.Errorf("dependency %s not found", dependencyName)
+	}
+	lock, err := pm.loadLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v", pm.lockPath, err)
+	}
+	pm.probeLock = lock
+	defer func() { pm.probeLock = nil }()
+
+	pm.trace = &decisionTrace{}
+	defer func() { pm.trace = nil }()
+
+	if !pm.isPlatformSupported(dep) {
+		pm.trace.record("platforms: %s/%s is not in %v, the dependency is skipped", runtime.GOOS, runtime.GOARCH, dep.Platforms)
+	}
+	_, resolveErr := pm.resolveDependency(ctx, dependencyName, dep)
+	if resolveErr != nil {
+		pm.trace.record("failed: %v", resolveErr)
+	}
+
+	fmt.Fprintf(pm.out, "\n🔍 Why %s:\n", dependencyName)
+	for i, step := range pm.trace.steps {
+		fmt.Fprintf(pm.out, "  %d. %s\n", i+1, step)
+	}
+	return resolveErr
+}
+func (pm *Manager) SelfUpdate(ctx context.Context, checkOnly, force bool) error {
+	fmt.Fprintln(pm.progress, "🔄 Checking for fracture updates...")
+
+	const repoOwner = "glitch-vpn"
+	const repoName = "fracture"
+	release, err := pm.getLatestRelease(ctx, repoOwner, repoName, false)
+	if err != nil {
+		return fmt.Errorf("failed to get latest release: %v", err)
+	}
+
+	fmt.Fprintf(pm.progress, "Current version: %s\n", pm.version)
+	fmt.Fprintf(pm.progress, "Latest version: %s\n", release.TagName)
+
+	if checkOnly {
+		switch {
+		case pm.version == "dev":
+			fmt.Fprintln(pm.progress, "Running a development build; cannot tell whether an update is available")
+		case sameReleaseVersion(pm.version, release.TagName):
+			fmt.Fprintln(pm.progress, "✅ fracture is up to date")
+		default:
+			fmt.Fprintf(pm.progress, "⬆️  Update available: %s -> %s\n", pm.version, release.TagName)
+		}
+		if release.HTMLURL != "" {
+			fmt.Fprintf(pm.progress, "Changelog: %s\n", release.HTMLURL)
+		}
+		return nil
+	}
+
+	if !force && pm.version != "dev" && sameReleaseVersion(pm.version, release.TagName) {
+		fmt.Fprintln(pm.progress, "✅ fracture is already up to date (use --force to reinstall)")
+		return nil
+	}
+
+	currentOS := runtime.GOOS
+	currentArch := runtime.GOARCH
+
+	fmt.Fprintf(pm.progress, "Current platform: %s/%s\n", currentOS, currentArch)
+	fmt.Fprintf(pm.progress, "Available assets:\n")
+	for i, asset := range release.Assets {
+		fmt.Fprintf(pm.progress, "  [%d] %s\n", i, asset.Name)
+	}
+
+	var downloadURL string
+	var assetName string
+	bestMatch := pm.findBestAssetMatch(release.Assets, currentOS, currentArch)
+	if bestMatch != nil {
+		downloadURL = bestMatch.BrowserDownloadURL
+		assetName = bestMatch.Name
+		fmt.Fprintf(pm.progress, "Selected asset: %s\n", assetName)
+	}
+
+	if downloadURL == "" {
+		return fmt.Errorf("no suitable binary found for %s/%s", currentOS, currentArch)
+	}
+
+	fmt.Fprintf(pm.progress, "Downloading %s...\n", assetName)
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %v", err)
+	}
+
+	tmpDir, err := pm.createTempDir()
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	downloadPath := filepath.Join(tmpDir, assetName)
+	err = pm.downloadBinary(ctx, downloadURL, downloadPath, false, 0755, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download update: %v", err)
+	}
+
+	err = pm.verifyReleaseChecksum(ctx, release, assetName, downloadPath)
+	if err != nil {
+		return fmt.Errorf("refusing to install update: %v", err)
+	}
+
+	var newBinaryPath string
+
+	if archiveFormat(assetName) == "tar.gz" {
+		extractDir := filepath.Join(tmpDir, "extracted")
+		pm.emit(Event{Kind: EventExtract, Dependency: "fracture", Version: release.TagName, Path: downloadPath})
+		err = pm.extractArchive(downloadPath, extractDir)
+		if err != nil {
+			return fmt.Errorf("failed to extract archive: %v", err)
+		}
+
+		newBinaryPath, err = findUpdateBinary(extractDir)
+		if err != nil {
+			return fmt.Errorf("failed to list extracted files: %v", err)
+		}
+
+		if newBinaryPath == "" {
+			return fmt.Errorf("no executable binary found in archive")
+		}
+	} else if strings.HasSuffix(assetName, ".zip") {
+		extractDir := filepath.Join(tmpDir, "extracted")
+		pm.emit(Event{Kind: EventExtract, Dependency: "fracture", Version: release.TagName, Path: downloadPath})
+		err = pm.extractArchive(downloadPath, extractDir)
+		if err != nil {
+			return fmt.Errorf("failed to extract ZIP archive: %v", err)
+		}
+
+		newBinaryPath, err = findUpdateBinary(extractDir)
+		if err != nil {
+			return fmt.Errorf("failed to list extracted files: %v", err)
+		}
+
+		if newBinaryPath == "" {
+			return fmt.Errorf("no executable binary found in ZIP archive")
+		}
+	} else {
+		newBinaryPath = downloadPath
+	}
+
+	err = os.Chmod(newBinaryPath, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to set executable permissions: %v", err)
+	}
+
+	fmt.Fprintln(pm.progress, "Testing new binary...")
+	cmd := exec.CommandContext(ctx, newBinaryPath, "version")
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("new binary failed to run: %v", err)
+	}
+	fmt.Fprintf(pm.progress, "New binary version output:\n%s", output)
+
+	err = replaceExecutable(ctx, newBinaryPath, execPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(pm.progress, "✅ Successfully updated to %s\n", release.TagName)
+	return nil
+}
+func (pm *Manager) verifyReleaseChecksum(ctx context.Context, release *GitHubRelease, assetName, path string) error {
+	var sumsURL string
+	for _, asset := range release.Assets {
+		if asset.Name == "SHA256SUMS" {
+			sumsURL = asset.BrowserDownloadURL
+			break
+		}
+	}
+	if sumsURL == "" {
+		return fmt.Errorf("release %s has no SHA256SUMS asset", release.TagName)
+	}
+
+	req, err := pm.newRequest(ctx, "GET", sumsURL)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := pm.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download SHA256SUMS: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("failed to download SHA256SUMS: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read SHA256SUMS: %v", err)
+	}
+
+	expected := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == assetName {
+			expected = strings.ToLower(fields[0])
+			break
+		}
+	}
+	if expected == "" {
+		return fmt.Errorf("SHA256SUMS has no entry for %s", assetName)
+	}
+
+	actual, err := fileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %v", assetName, err)
+	}
+	if actual != expected {
+		return kindErrorf(ErrChecksumMismatch, "checksum mismatch for %s: expected %s, got %s", assetName, expected, actual)
+	}
+
+	fmt.Fprintf(pm.progress, "Verified SHA256 checksum of %s\n", assetName)
+	return nil
+}
+
+type semver struct {
+	major, minor, patch int
+	prerelease          string
+}
+
+func parseSemver(version string) (semver, error) {
+	core := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	core, _, _ = strings.Cut(core, "+")
+	core, prerelease, _ := strings.Cut(core, "-")
+
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return semver{}, fmt.Errorf("invalid version %q", version)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return semver{}, fmt.Errorf("invalid version %q", version)
+		}
+		numbers[i] = number
+	}
+	return semver{major: numbers[0], minor: numbers[1], patch: numbers[2], prerelease: prerelease}, nil
+}
+func compareSemver(a, b semver) int {
+	for _, diff := range []int{a.major - b.major, a.minor - b.minor, a.patch - b.patch} {
+		if diff != 0 {
+			if diff < 0 {
+				return -1
+			}
+			return 1
+		}
+	}
+	// A prerelease sorts before the release it precedes.
+	switch {
+	case a.prerelease == b.prerelease:
+		return 0
+	case a.prerelease == "":
+		return 1
+	case b.prerelease == "":
+		return -1
+	}
+	return comparePrerelease(a.prerelease, b.prerelease)
+}
+func comparePrerelease(a, b string) int {
+	// Dot-separated identifiers compare one by one: numbers numerically
+	// (rc.2 < rc.10), and below any identifier with letters.
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNumber, aErr := strconv.Atoi(aParts[i])
+		bNumber, bErr := strconv.Atoi(bParts[i])
+		switch {
+		case aErr == nil && bErr == nil && aNumber != bNumber:
+			if aNumber < bNumber {
+				return -1
+			}
+			return 1
+		case aErr == nil && bErr != nil:
+			return -1
+		case aErr != nil && bErr == nil:
+			return 1
+		case aParts[i] < bParts[i]:
+			return -1
+		case aParts[i] > bParts[i]:
+			return 1
+		}
+	}
+	switch {
+	case len(aParts) < len(bParts):
+		return -1
+	case len(aParts) > len(bParts):
+		return 1
+	}
+	return 0
+}
+
+// satisfiesConstraint checks a version against a comma-separated list of
+// constraints such as ">=1.6, <2". Supported operators are =, !=, >, >=, <,
+// <=, ^ (same major) and ~ (same minor).
+func satisfiesConstraint(version, constraint string) (bool, error) {
+	current, err := parseSemver(version)
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(constraint) == "" {
+		return false, fmt.Errorf("empty version constraint")
+	}
+
+	for _, term := range strings.Split(constraint, ",") {
+		term = strings.TrimSpace(term)
+		operator := "="
+		for _, candidate := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(term, candidate) {
+				operator = candidate
+				term = strings.TrimSpace(strings.TrimPrefix(term, candidate))
+				break
+			}
+		}
+		wanted, err := parseSemver(term)
+		if err != nil {
+			return false, fmt.Errorf("invalid constraint %q: %v", constraint, err)
+		}
+
+		cmp := compareSemver(current, wanted)
+		var ok bool
+		switch operator {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "^":
+			ok = cmp >= 0 && current.major == wanted.major
+		case "~":
+			ok = cmp >= 0 && current.major == wanted.major && current.minor == wanted.minor
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+func sameReleaseVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+func findUpdateBinary(extractDir string) (string, error) {
+	binaryName := "fracture"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+
+	files, err := filepath.Glob(filepath.Join(extractDir, "*"))
+	if err != nil {
+		return "", err
+	}
+
+	var candidate string
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		name := filepath.Base(file)
+		if name == binaryName {
+			return file, nil
+		}
+		if candidate != "" {
+			continue
+		}
+		if runtime.GOOS == "windows" {
+			if strings.HasSuffix(strings.ToLower(name), ".exe") {
+				candidate = file
+			}
+		} else if info.Mode()&0111 != 0 || strings.Contains(name, "fracture") {
+			candidate = file
+		}
+	}
+
+	return candidate, nil
+}
+func replaceExecutable(ctx context.Context, newBinaryPath, execPath string) error {
+	tempExecPath := execPath + ".tmp"
+	err := movePath(newBinaryPath, tempExecPath)
+	if err != nil {
+		return fmt.Errorf("failed to move new binary: %v", err)
+	}
+
+	// The running binary is moved aside rather than overwritten: Windows
+	// refuses to overwrite a running executable, and the copy doubles as a
+	// backup if the new binary turns out to be broken.
+	oldExecPath := execPath + ".old"
+	os.Remove(oldExecPath)
+	err = os.Rename(execPath, oldExecPath)
+	if err != nil {
+		os.Remove(tempExecPath)
+		return fmt.Errorf("failed to back up current binary: %v", err)
+	}
+
+	err = os.Rename(tempExecPath, execPath)
+	if err == nil {
+		err = exec.CommandContext(ctx, execPath, "version").Run()
+		if err != nil {
+			err = fmt.Errorf("installed binary failed to run: %v", err)
+		}
+	} else {
+		err = fmt.Errorf("failed to replace binary: %v", err)
+	}
+	if err != nil {
+		os.Remove(tempExecPath)
+		os.Remove(execPath)
+		restoreErr := os.Rename(oldExecPath, execPath)
+		if restoreErr != nil {
+			return fmt.Errorf("%v; restoring the previous binary also failed, it is kept at %s: %v", err, oldExecPath, restoreErr)
+		}
+		return fmt.Errorf("%v; restored the previous binary", err)
+	}
+
+	// Windows keeps the old binary locked until this process exits, so it is
+	// removed on the next start instead.
+	if runtime.GOOS != "windows" {
+		os.Remove(oldExecPath)
+	}
+
+	return nil
+}
+func RemoveOldExecutable() {
+	if runtime.GOOS != "windows" {
+		return
+	}
+	execPath, err := os.Executable()
+	if err != nil {
+		return
+	}
+	// Left behind by a Windows self-update; may still be locked if the
+	// previous process hasn't exited yet, in which case we try again later.
+	os.Remove(execPath + ".old")
+}
+
+func (pm *Manager) findBestAssetMatch(assets []GitHubAsset, targetOS, targetArch string) *GitHubAsset {
+	patterns := []string{
+		fmt.Sprintf("%s_%s", targetOS, targetArch),
+		fmt.Sprintf("%s-%s", targetOS, targetArch),
+		fmt.Sprintf("%s.%s", targetOS, targetArch),
+	}
+
+	if targetOS == "darwin" {
+		patterns = append(patterns, fmt.Sprintf("macos_%s", targetArch))
+		patterns = append(patterns, fmt.Sprintf("macos-%s", targetArch))
+		patterns = append(patterns, fmt.Sprintf("mac_%s", targetArch))
+		patterns = append(patterns, fmt.Sprintf("mac-%s", targetArch))
+	}
+
+	if targetOS == "windows" {
+		patterns = append(patterns, fmt.Sprintf("win_%s", targetArch))
+		patterns = append(patterns, fmt.Sprintf("win-%s", targetArch))
+		patterns = append(patterns, fmt.Sprintf("win32_%s", targetArch))
+		patterns = append(patterns, fmt.Sprintf("win32-%s", targetArch))
+		// A bare .exe is preferred over an archive for the same platform.
+		exePatterns := make([]string, 0, len(patterns))
+		for _, pattern := range patterns {
+			exePatterns = append(exePatterns, pattern+".exe")
+		}
+		patterns = append(exePatterns, patterns...)
+	}
+
+	if aliases, exists := archAliases[targetArch]; exists {
+		for _, alias := range aliases {
+			patterns = append(patterns, fmt.Sprintf("%s_%s", targetOS, alias))
+			patterns = append(patterns, fmt.Sprintf("%s-%s", targetOS, alias))
+			if targetOS == "windows" {
+				patterns = append(patterns, fmt.Sprintf("%s_%s.exe", targetOS, alias))
+				patterns = append(patterns, fmt.Sprintf("%s-%s.exe", targetOS, alias))
+			}
+		}
+	}
+
+	for _, pattern := range patterns {
+		for i := range assets {
+			assetName := strings.ToLower(assets[i].Name)
+			if strings.Contains(assetName, strings.ToLower(pattern)) {
+				fmt.Fprintf(pm.progress, "Found exact match with pattern '%s': %s\n", pattern, assets[i].Name)
+				return &assets[i]
+			}
+		}
+	}
+
+	for i := range assets {
+		assetName := strings.ToLower(assets[i].Name)
+		containsOS := strings.Contains(assetName, targetOS)
+		containsArch := strings.Contains(assetName, targetArch)
+
+		if !containsArch {
+			if aliases, exists := archAliases[targetArch]; exists {
+				for _, alias := range aliases {
+					if strings.Contains(assetName, alias) {
+						containsArch = true
+						break
+					}
+				}
+			}
+		}
+
+		if !containsOS && targetOS == "darwin" {
+			containsOS = strings.Contains(assetName, "macos") || strings.Contains(assetName, "mac")
+		}
+		if !containsOS && targetOS == "windows" {
+			containsOS = strings.Contains(assetName, "win") || strings.Contains(assetName, "win32")
+		}
+
+		if containsOS && containsArch {
+			fmt.Fprintf(pm.progress, "Found fallback match: %s (contains %s and %s)\n", assets[i].Name, targetOS, targetArch)
+			return &assets[i]
+		}
+	}
+
+	return nil
+}
+func isTruthyEnv(name string) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && value
+}
+
+func (pm *Manager) getAssetSuffixFromDep(dep Dependency) string {
+	return dep.AssetSuffix
+}
+
+func (pm *Manager) expandPath(path, version string) string {
+	return pm.expandPathWithOptions(path, version, "", false)
+}
+
+func (pm *Manager) expandPathWithOptions(path, version, assetExtension string, extractMode bool) string {
+	expanded := path
+
+	if strings.Contains(expanded, "@VERSION") {
+		expanded = strings.ReplaceAll(expanded, "@VERSION", version)
+	}
+
+	if strings.Contains(expanded, "@TIMESTAMP") {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		expanded = strings.ReplaceAll(expanded, "@TIMESTAMP", timestamp)
+	}
+
+	expanded = expandPlatformVars(expanded)
+
+	if strings.Contains(expanded, "@ASSET_EXTENSION") {
+		if extractMode {
+			expanded = strings.ReplaceAll(expanded, "@ASSET_EXTENSION", "")
+		} else if assetExtension != "" {
+			expanded = strings.ReplaceAll(expanded, "@ASSET_EXTENSION", assetExtension)
+		} else {
+			expanded = strings.ReplaceAll(expanded, "@ASSET_EXTENSION", "")
+		}
+	}
+
+	return expandEnvVars(expanded)
+}
+
+var envVarPattern = regexp.MustCompile(`\$([A-Z_][A-Z0-9_]*)`)
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func expandEnvVars(value string) string {
+	return envVarPattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+		return os.Getenv(strings.TrimPrefix(placeholder, "$"))
+	})
+}
+func expandPlatformVars(value string) string {
+	value = strings.ReplaceAll(value, "@OS", runtime.GOOS)
+	return strings.ReplaceAll(value, "@ARCH", runtime.GOARCH)
+}
+func unsetEnvVars(value string) []string {
+	var unset []string
+	for _, match := range envVarPattern.FindAllStringSubmatch(value, -1) {
+		if _, ok := os.LookupEnv(match[1]); !ok {
+			unset = append(unset, "$"+match[1])
+		}
+	}
+	return unset
+}
@@ -0,0 +1,160 @@
+package fracture
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDropCredentialHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret")
+	header.Set("Private-Token", "secret")
+	header.Set("X-Api-Key", "secret")
+	header.Set("Accept", "application/octet-stream")
+	header.Set("Accept-Encoding", "gzip")
+	header.Set("User-Agent", "fracture")
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	header.Set("Range", "bytes=10-")
+
+	dropped := dropCredentialHeaders(header)
+	if want := []string{"Authorization", "Private-Token", "X-Api-Key"}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped = %v, want %v", dropped, want)
+	}
+	for _, name := range []string{"Accept", "Accept-Encoding", "User-Agent", "X-GitHub-Api-Version", "Range"} {
+		if header.Get(name) == "" {
+			t.Errorf("%s was dropped, want it kept", name)
+		}
+	}
+	for _, name := range dropped {
+		if header.Get(name) != "" {
+			t.Errorf("%s is still set", name)
+		}
+	}
+
+	if dropped := dropCredentialHeaders(http.Header{"Accept": {"*/*"}}); len(dropped) != 0 {
+		t.Errorf("dropped = %v from harmless headers, want none", dropped)
+	}
+}
+func TestCheckRedirect(t *testing.T) {
+	two := 2
+	tests := []struct {
+		name    string
+		opts    Options
+		chain   []string
+		wantErr string
+	}{
+		{name: "within the default limit", chain: []string{"https://a.example/1", "https://a.example/2"}},
+		{name: "at the configured limit", opts: Options{MaxRedirects: &two}, chain: []string{"https://a.example/1", "https://a.example/2", "https://a.example/3"}},
+		{name: "past the configured limit", opts: Options{MaxRedirects: &two}, chain: []string{"https://a.example/1", "https://a.example/2", "https://a.example/3", "https://a.example/4"}, wantErr: "stopped after 2 redirects"},
+		{name: "https to http", chain: []string{"https://a.example/1", "http://b.example/2"}, wantErr: "refusing redirect from HTTPS to plain HTTP"},
+		{name: "https to http allowed", opts: Options{AllowInsecureHTTP: true}, chain: []string{"https://a.example/1", "http://b.example/2"}},
+		{name: "http to http", chain: []string{"http://a.example/1", "http://b.example/2"}},
+		{name: "host not allowed", opts: Options{AllowedHosts: []string{"a.example"}}, chain: []string{"https://a.example/1", "https://b.example/2"}, wantErr: "host b.example is not allowed"},
+		{name: "subdomain allowed", opts: Options{AllowedHosts: []string{"example"}}, chain: []string{"https://a.example/1", "https://b.example/2"}},
+	}
+	for _, tt := range tests {
+		client, err := newHTTPClient(tt.opts, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var via []*http.Request
+		for _, rawURL := range tt.chain[:len(tt.chain)-1] {
+			req, _ := http.NewRequest("GET", rawURL, nil)
+			via = append(via, req)
+		}
+		req, _ := http.NewRequest("GET", tt.chain[len(tt.chain)-1], nil)
+		err = client.CheckRedirect(req, via)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: CheckRedirect = %v, want nil", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: CheckRedirect = %v, want an error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+func TestCheckRedirectDropsCredentialsAcrossHosts(t *testing.T) {
+	client, err := newHTTPClient(Options{}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := http.NewRequest("GET", "https://api.github.com/repos/o/r/releases/assets/1", nil)
+	sameHost, _ := http.NewRequest("GET", "https://api.github.com/other", nil)
+	sameHost.Header.Set("Authorization", "Bearer secret")
+	if err := client.CheckRedirect(sameHost, []*http.Request{first}); err != nil {
+		t.Fatal(err)
+	}
+	if sameHost.Header.Get("Authorization") == "" {
+		t.Error("Authorization dropped on a same-host redirect")
+	}
+	otherHost, _ := http.NewRequest("GET", "https://objects.githubusercontent.com/asset", nil)
+	otherHost.Header.Set("Authorization", "Bearer secret")
+	if err := client.CheckRedirect(otherHost, []*http.Request{first}); err != nil {
+		t.Fatal(err)
+	}
+	if otherHost.Header.Get("Authorization") != "" {
+		t.Error("Authorization kept on a cross-host redirect")
+	}
+}
+
+type recordingTransport struct {
+	paths   []string
+	handler http.Handler
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.paths = append(rt.paths, req.URL.RequestURI())
+	recorder := httptest.NewRecorder()
+	rt.handler.ServeHTTP(recorder, req)
+	return recorder.Result(), nil
+}
+func TestReleaseListingPages(t *testing.T) {
+	const total = 150
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/releases/latest") {
+			json.NewEncoder(w).Encode(GitHubRelease{TagName: "v0"})
+			return
+		}
+		var perPage, page int
+		fmt.Sscanf(r.URL.Query().Get("per_page"), "%d", &perPage)
+		fmt.Sscanf(r.URL.Query().Get("page"), "%d", &page)
+		var batch []GitHubRelease
+		for i := (page - 1) * perPage; i < page*perPage && i < total; i++ {
+			batch = append(batch, GitHubRelease{TagName: fmt.Sprintf("v%d", i)})
+		}
+		json.NewEncoder(w).Encode(batch)
+	})
+
+	tests := []struct {
+		name  string
+		run   func(pm *Manager) error
+		paths []string
+	}{
+		{"semver resolution lists every page", func(pm *Manager) error {
+			releases, err := pm.listReleases(context.Background(), "o", "r", false)
+			if err == nil && len(releases) != total {
+				err = fmt.Errorf("listed %d releases", len(releases))
+			}
+			return err
+		}, []string{"/repos/o/r/releases?per_page=100&page=1", "/repos/o/r/releases?per_page=100&page=2"}},
+		{"search asks for one page", func(pm *Manager) error {
+			return pm.Search(context.Background(), "o/r", "")
+		}, []string{"/repos/o/r/releases?per_page=10&page=1", "/repos/o/r/releases/latest"}},
+	}
+	for _, tt := range tests {
+		transport := &recordingTransport{handler: handler}
+		pm := &Manager{httpClient: &http.Client{Transport: transport}, userAgent: "fracture/test", out: io.Discard, progress: io.Discard}
+		if err := tt.run(pm); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(transport.paths, tt.paths) {
+			t.Errorf("%s: requested %v, want %v", tt.name, transport.paths, tt.paths)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package fracture
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInstallOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		deps    DepsFile
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "no requirements sorts by name",
+			deps: DepsFile{"c": {}, "a": {}, "b": {}},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "requirements come first",
+			deps: DepsFile{"a": {Requires: []string{"c"}}, "b": {}, "c": {Requires: []string{"b"}}},
+			want: []string{"b", "c", "a"},
+		},
+		{
+			name: "shared requirement installed once",
+			deps: DepsFile{"app": {Requires: []string{"lib", "tool"}}, "tool": {Requires: []string{"lib"}}, "lib": {}},
+			want: []string{"lib", "tool", "app"},
+		},
+		{
+			name:    "cycle",
+			deps:    DepsFile{"a": {Requires: []string{"b"}}, "b": {Requires: []string{"c"}}, "c": {Requires: []string{"b"}}},
+			wantErr: "dependency cycle: b -> c -> b",
+		},
+		{
+			name:    "requires itself",
+			deps:    DepsFile{"a": {Requires: []string{"a"}}},
+			wantErr: "dependency cycle: a -> a",
+		},
+		{
+			name:    "unknown requirement",
+			deps:    DepsFile{"a": {Requires: []string{"missing"}}},
+			wantErr: "dependency a requires unknown dependency missing",
+		},
+	}
+	for _, tt := range tests {
+		got, err := installOrder(tt.deps)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: installOrder error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: installOrder = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: installOrder = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+func TestCheckPathCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		deps    DepsFile
+		wantErr string
+	}{
+		{
+			name: "distinct paths",
+			deps: DepsFile{"a": {Path: "vendor/a", Type: "repository"}, "b": {Path: "vendor/b", Type: "repository"}},
+		},
+		{
+			name:    "two repositories",
+			deps:    DepsFile{"a": {Path: "vendor/x", Type: "repository"}, "b": {Path: "vendor/./x/", Type: "repository"}},
+			wantErr: "a and b both install to vendor/./x/",
+		},
+		{
+			name:    "two extracted archives",
+			deps:    DepsFile{"a": {Path: "tools", Type: "binary", Extract: true}, "b": {Path: "tools", Type: "source", Extract: true}},
+			wantErr: "a and b both install to tools",
+		},
+		{
+			name: "binaries sharing a directory",
+			deps: DepsFile{"a": {Path: "bin", Type: "binary", Filename: "a"}, "b": {Path: "bin", Type: "binary", Filename: "b"}},
+		},
+		{
+			name:    "binaries writing the same file",
+			deps:    DepsFile{"a": {Path: "bin", Type: "binary", Filename: "tool"}, "b": {Path: "bin", Type: "binary", AssetName: "tool"}},
+			wantErr: "a and b both install bin/tool",
+		},
+		{
+			name: "asset glob is not a file name",
+			deps: DepsFile{"a": {Path: "bin", Type: "binary", AssetName: "tool-*"}, "b": {Path: "bin", Type: "binary", AssetName: "tool-*"}},
+		},
+		{
+			name:    "file inside a repository",
+			deps:    DepsFile{"repo": {Path: "vendor/repo", Type: "repository"}, "tool": {Path: "vendor/repo", Type: "binary", Filename: "tool"}},
+			wantErr: "tool installs into the path of repo",
+		},
+		{
+			name: "path variables are skipped",
+			deps: DepsFile{"a": {Path: "tools/@VERSION", Type: "repository"}, "b": {Path: "tools/@VERSION", Type: "repository"}},
+		},
+		{
+			name:    "version fills in @VERSION",
+			deps:    DepsFile{"a": {Path: "tools/@VERSION", Type: "repository", Version: "v1"}, "b": {Path: "tools/v1", Type: "repository"}},
+			wantErr: "a and b both install to tools/v1",
+		},
+		{
+			name: "other platforms are skipped",
+			deps: DepsFile{"a": {Path: "x", Type: "repository"}, "b": {Path: "x", Type: "repository", Platforms: []string{"plan9/none"}}},
+		},
+	}
+	for _, tt := range tests {
+		pm := &Manager{workDir: t.TempDir()}
+		err := pm.checkPathCollisions(tt.deps)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: checkPathCollisions = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: checkPathCollisions = %v, want an error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package fracture
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrateLockFile(t *testing.T) {
+	tool := LockDependency{Name: "tool", Path: "bin", Source: "https://github.com/o/r", Version: "v1.0.0", Hash: "abc", Type: "binary"}
+	tests := []struct {
+		name    string
+		data    string
+		version int
+		want    LockFile
+	}{
+		{
+			name:    "bare map",
+			data:    `{"tool": {"name": "tool", "path": "bin", "source": "https://github.com/o/r", "version": "v1.0.0", "hash": "abc", "type": "binary"}}`,
+			version: 0,
+			want:    LockFile{"tool": tool},
+		},
+		{
+			name:    "empty bare map",
+			data:    `{}`,
+			version: 0,
+			want:    LockFile{},
+		},
+		{
+			name:    "current schema",
+			data:    `{"schema_version": 1, "dependencies": {"tool": {"name": "tool", "path": "bin", "source": "https://github.com/o/r", "version": "v1.0.0", "hash": "abc", "type": "binary"}}}`,
+			version: 1,
+			want:    LockFile{"tool": tool},
+		},
+		{
+			name:    "current schema without dependencies",
+			data:    `{"schema_version": 1}`,
+			version: 1,
+			want:    LockFile{},
+		},
+	}
+	for _, tt := range tests {
+		got, err := migrateLockFile([]byte(tt.data), tt.version)
+		if err != nil {
+			t.Errorf("%s: migrateLockFile = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: migrateLockFile = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := migrateLockFile([]byte(`{"tool": "not an object"}`), 0); err == nil {
+		t.Error("migrateLockFile of a malformed bare map succeeded, want an error")
+	}
+}
+func TestLoadLockFileSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantNames    []string
+		wantMigrated bool
+		wantErr      string
+	}{
+		{name: "bare map", data: `{"tool": {"name": "tool"}}`, wantNames: []string{"tool"}, wantMigrated: true},
+		{name: "dependency named schema_version", data: `{"schema_version": {"name": "schema_version"}}`, wantNames: []string{"schema_version"}, wantMigrated: true},
+		{name: "current schema", data: `{"schema_version": 1, "dependencies": {"tool": {"name": "tool"}}}`, wantNames: []string{"tool"}},
+		{name: "newer schema", data: `{"schema_version": 2, "dependencies": {}}`, wantErr: "lock schema version 2 is newer"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, LockFileName), []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		pm := &Manager{workDir: dir, lockPath: LockFileName}
+		lock, err := pm.loadLockFile()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: loadLockFile error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: loadLockFile = %v", tt.name, err)
+			continue
+		}
+		var names []string
+		for name := range lock {
+			names = append(names, name)
+		}
+		if !reflect.DeepEqual(names, tt.wantNames) {
+			t.Errorf("%s: lock names = %v, want %v", tt.name, names, tt.wantNames)
+		}
+		if migrated := pm.lockMigrated != nil; migrated != tt.wantMigrated {
+			t.Errorf("%s: migrated = %v, want %v", tt.name, migrated, tt.wantMigrated)
+		}
+	}
+}
+func TestSameVersion(t *testing.T) {
+	sha1 := strings.Repeat("ab", 20)
+	sha256 := strings.Repeat("cd", 32)
+	tests := []struct {
+		name     string
+		locked   string
+		resolved string
+		want     bool
+	}{
+		{"identical tags", "v1.0.0", "v1.0.0", true},
+		{"different tags", "v1.0.0", "v1.1.0", false},
+		{"truncated SHA-1 lock", sha1[:8], sha1, true},
+		{"truncated SHA-256 lock", sha256[:8], sha256, true},
+		{"full SHA-256 lock", sha256, sha256, true},
+		{"other commit", strings.Repeat("0", 8), sha256, false},
+		{"truncated resolution", sha1, sha1[:8], false},
+	}
+	for _, tt := range tests {
+		if got := sameVersion(tt.locked, tt.resolved); got != tt.want {
+			t.Errorf("%s: sameVersion = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+func TestIsFullCommitHash(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{strings.Repeat("a", 40), true},
+		{strings.Repeat("a", 64), true},
+		{strings.Repeat("a", 8), false},
+		{strings.Repeat("a", 50), false},
+		{strings.Repeat("g", 40), false},
+		{"unknown", false},
+	}
+	for _, tt := range tests {
+		if got := isFullCommitHash(tt.value); got != tt.want {
+			t.Errorf("isFullCommitHash(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+func TestResolveRepositoryDependencyUnreachable(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	pm := &Manager{out: io.Discard, progress: io.Discard}
+	source := filepath.Join(t.TempDir(), "missing.git")
+	resolved, err := pm.resolveRepositoryDependency(context.Background(), "repo", Dependency{Source: source, Path: "repo", Type: "repository"})
+	if err == nil {
+		t.Fatalf("resolved an unreachable repository as %+v", resolved)
+	}
+}
+func TestProbeRawFile(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method+" "+r.Header.Get("Range"))
+		switch {
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/no-head" && r.Method == "HEAD":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.Write([]byte("content"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		locked      LockDependency
+		wantVersion string
+		wantMethods []string
+		wantErr     error
+	}{
+		{"locked file keeps its hash", "/tool", LockDependency{Type: "file", URL: server.URL + "/tool", Version: "locked"}, "locked", []string{"HEAD "}, nil},
+		{"lock entry for another URL", "/tool", LockDependency{Type: "file", URL: server.URL + "/old", Version: "locked"}, "", []string{"HEAD "}, nil},
+		{"new file", "/tool", LockDependency{}, "", []string{"HEAD "}, nil},
+		{"server without HEAD", "/no-head", LockDependency{}, "", []string{"HEAD ", "GET bytes=0-0"}, nil},
+		{"missing file", "/missing", LockDependency{}, "", []string{"HEAD "}, ErrNotFound},
+	}
+	for _, tt := range tests {
+		methods = nil
+		pm := &Manager{httpClient: server.Client(), allowHTTP: true, out: io.Discard, progress: io.Discard}
+		version, asset, err := pm.probeRawFile(context.Background(), server.URL+tt.path, nil, tt.locked)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if version != tt.wantVersion || asset.BrowserDownloadURL != server.URL+tt.path {
+			t.Errorf("%s: got version %q and %+v", tt.name, version, asset)
+		}
+		if !reflect.DeepEqual(methods, tt.wantMethods) {
+			t.Errorf("%s: requests %v, want %v", tt.name, methods, tt.wantMethods)
+		}
+	}
+}
+func TestLockDoesNotDownloadRawFiles(t *testing.T) {
+	var gets int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			gets++
+		}
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	deps := DepsFile{
+		"locked": {Source: server.URL + "/locked", Type: "file", Path: "bin"},
+		"new":    {Source: server.URL + "/new", Type: "file", Path: "bin"},
+	}
+	lock := LockFile{
+		"locked": {Name: "locked", Source: server.URL + "/locked", Type: "file", Path: "bin", Version: "recorded", Hash: "recorded", URL: server.URL + "/locked", Asset: "locked", SHA256: "recorded"},
+	}
+	pm := newTestProject(t, deps, lock, Options{AllowInsecureHTTP: true})
+	if err := pm.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	newLock, err := pm.loadLockFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gets != 0 {
+		t.Errorf("lock sent %d GET requests", gets)
+	}
+	if got := newLock["locked"]; got.Version != "recorded" || got.SHA256 != "recorded" {
+		t.Errorf("locked raw file = %+v, want its recorded hash", got)
+	}
+	if got, exists := newLock["new"]; exists {
+		t.Errorf("raw file with unknown content was locked as %+v", got)
+	}
+}
@@ -0,0 +1,137 @@
+package fracture
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONMembers(t *testing.T) {
+	data := []byte(`{"a": 1, "b" : {"c": [1, 2]}, "d": "x"}`)
+	members, err := jsonMembers(data, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ name, key, value string }{
+		{name: "a", key: `"a"`, value: `1`},
+		{name: "b", key: `"b"`, value: `{"c": [1, 2]}`},
+		{name: "d", key: `"d"`, value: `"x"`},
+	}
+	if len(members) != len(want) {
+		t.Fatalf("got %d members, want %d", len(members), len(want))
+	}
+	for i, member := range members {
+		if member.name != want[i].name {
+			t.Errorf("member %d name = %q, want %q", i, member.name, want[i].name)
+		}
+		if key := string(data[member.keyStart : member.keyStart+len(want[i].key)]); key != want[i].key {
+			t.Errorf("member %s key at %d = %q, want %q", member.name, member.keyStart, key, want[i].key)
+		}
+		if value := string(data[member.valueStart:member.valueEnd]); value != want[i].value {
+			t.Errorf("member %s value = %q, want %q", member.name, value, want[i].value)
+		}
+	}
+
+	nested, err := jsonMembers(data, members[1].valueStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nested) != 1 || nested[0].name != "c" || string(data[nested[0].valueStart:nested[0].valueEnd]) != "[1, 2]" {
+		t.Errorf("nested members = %+v", nested)
+	}
+
+	if _, err := jsonMembers([]byte(`[1]`), 0); err == nil {
+		t.Error("jsonMembers on an array succeeded, want an error")
+	}
+}
+
+func TestSetConfigVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		version string
+		want    string
+	}{
+		{
+			name:    "add to a multi-line object",
+			config:  "{\n  // tools\n  \"tool\": {\n    \"path\": \"bin\",\n    \"source\": \"https://github.com/o/r\"\n  }\n}\n",
+			version: "v1.2.3",
+			want:    "{\n  // tools\n  \"tool\": {\n    \"path\": \"bin\",\n    \"source\": \"https://github.com/o/r\",\n    \"version\": \"v1.2.3\"\n  }\n}\n",
+		},
+		{
+			name:    "add to a one-line object",
+			config:  `{"tool": {"path": "bin", "source": "x"}}`,
+			version: "v1",
+			want:    `{"tool": {"path": "bin", "source": "x", "version": "v1"}}`,
+		},
+		{
+			name:    "replace",
+			config:  `{"tool": {"version": "v1", "source": "x"}, "other": {"version": "v9", "source": "y"}}`,
+			version: "v2",
+			want:    `{"tool": {"version": "v2", "source": "x"}, "other": {"version": "v9", "source": "y"}}`,
+		},
+		{
+			name:    "remove the first member",
+			config:  `{"tool": {"version": "v1", "source": "x"}}`,
+			version: "",
+			want:    `{"tool": {"source": "x"}}`,
+		},
+		{
+			name:    "remove a later member",
+			config:  "{\"tool\": {\n  \"source\": \"x\",\n  \"version\": \"v1\" // pinned\n}}",
+			version: "",
+			want:    "{\"tool\": {\n  \"source\": \"x\" // pinned\n}}",
+		},
+		{
+			name:    "remove the only member",
+			config:  `{"tool": {"version": "v1"}}`,
+			version: "",
+			want:    `{"tool": {}}`,
+		},
+		{
+			name:    "structured config",
+			config:  `{"defaults": {"private": true}, "dependencies": {"tool": {"source": "x"}}}`,
+			version: "v3",
+			want:    `{"defaults": {"private": true}, "dependencies": {"tool": {"source": "x", "version": "v3"}}}`,
+		},
+		{
+			name:    "nothing to remove",
+			config:  `{"tool": {"source": "x"}}`,
+			version: "",
+			want:    `{"tool": {"source": "x"}}`,
+		},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		configPath := filepath.Join(dir, DepsFileName)
+		if err := os.WriteFile(configPath, []byte(tt.config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		pm := &Manager{workDir: dir, configPath: DepsFileName}
+		if err := pm.setConfigVersion("tool", tt.version); err != nil {
+			t.Errorf("%s: setConfigVersion = %v", tt.name, err)
+			continue
+		}
+		got, err := os.ReadFile(configPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+		if info, err := os.Stat(configPath); err == nil && info.Mode().Perm() != 0600 {
+			t.Errorf("%s: mode = %v, want 0600", tt.name, info.Mode().Perm())
+		}
+	}
+}
+
+func TestSetConfigVersionUnknownDependency(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, DepsFileName), []byte(`{"tool": {"source": "x"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pm := &Manager{workDir: dir, configPath: DepsFileName}
+	if err := pm.setConfigVersion("missing", "v1"); err == nil {
+		t.Error("setConfigVersion for a missing dependency succeeded, want an error")
+	}
+}
@@ -0,0 +1,99 @@
+package fracture
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyReleaseChecksum(t *testing.T) {
+	content := []byte("fracture binary")
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	other := strings.Repeat("0", 64)
+
+	tests := []struct {
+		name         string
+		sums         string
+		status       int
+		noSums       bool
+		wantErr      string
+		wantMismatch bool
+	}{
+		{name: "text mode entry", sums: other + "  fracture_linux_arm64\n" + hash + "  fracture_linux_amd64\n"},
+		{name: "binary mode entry", sums: hash + " *fracture_linux_amd64\n"},
+		{name: "upper case hash", sums: strings.ToUpper(hash) + "  fracture_linux_amd64\n"},
+		{name: "windows line endings", sums: hash + "  fracture_linux_amd64\r\n"},
+		{name: "first matching entry wins", sums: hash + "  fracture_linux_amd64\n" + other + "  fracture_linux_amd64\n"},
+		{name: "prefix of another asset", sums: hash + "  fracture_linux_amd64.sig\n", wantErr: "SHA256SUMS has no entry for fracture_linux_amd64"},
+		{name: "malformed lines ignored", sums: hash + "\n" + hash + "  fracture_linux_amd64 extra\n", wantErr: "SHA256SUMS has no entry"},
+		{name: "mismatch", sums: other + "  fracture_linux_amd64\n", wantErr: "checksum mismatch for fracture_linux_amd64", wantMismatch: true},
+		{name: "download fails", status: http.StatusNotFound, wantErr: "failed to download SHA256SUMS: status 404"},
+		{name: "no SHA256SUMS asset", noSums: true, wantErr: "release v1.0.0 has no SHA256SUMS asset"},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.status != 0 {
+				w.WriteHeader(tt.status)
+				return
+			}
+			w.Write([]byte(tt.sums))
+		}))
+		release := &GitHubRelease{TagName: "v1.0.0", Assets: []GitHubAsset{{Name: "fracture_linux_amd64", BrowserDownloadURL: server.URL + "/fracture_linux_amd64"}}}
+		if !tt.noSums {
+			release.Assets = append(release.Assets, GitHubAsset{Name: "SHA256SUMS", BrowserDownloadURL: server.URL + "/SHA256SUMS"})
+		}
+		path := filepath.Join(t.TempDir(), "fracture")
+		if err := os.WriteFile(path, content, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		pm := &Manager{httpClient: server.Client(), userAgent: "fracture/test", out: io.Discard, progress: io.Discard}
+		err := pm.verifyReleaseChecksum(context.Background(), release, "fracture_linux_amd64", path)
+		server.Close()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: verifyReleaseChecksum = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: verifyReleaseChecksum = %v, want an error containing %q", tt.name, err, tt.wantErr)
+		}
+		if mismatch := errors.Is(err, ErrChecksumMismatch); mismatch != tt.wantMismatch {
+			t.Errorf("%s: errors.Is(err, ErrChecksumMismatch) = %v, want %v", tt.name, mismatch, tt.wantMismatch)
+		}
+	}
+}
+func TestFindBestAssetMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		os     string
+		assets []string
+		want   string
+	}{
+		{"windows prefers the exe", "windows", []string{"fracture_windows_amd64.zip", "fracture_windows_amd64.exe"}, "fracture_windows_amd64.exe"},
+		{"windows archive without an exe", "windows", []string{"fracture_linux_amd64", "fracture_windows_amd64.zip"}, "fracture_windows_amd64.zip"},
+		{"windows short name", "windows", []string{"fracture_win-amd64.zip"}, "fracture_win-amd64.zip"},
+		{"linux", "linux", []string{"fracture_windows_amd64.exe", "fracture_linux_amd64"}, "fracture_linux_amd64"},
+	}
+	for _, tt := range tests {
+		var assets []GitHubAsset
+		for _, name := range tt.assets {
+			assets = append(assets, GitHubAsset{Name: name})
+		}
+		pm := &Manager{out: io.Discard, progress: io.Discard}
+		got := pm.findBestAssetMatch(assets, tt.os, "amd64")
+		if got == nil || got.Name != tt.want {
+			t.Errorf("%s: got %+v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package fracture
+
+import "testing"
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		version string
+		want    semver
+		wantErr bool
+	}{
+		{version: "1.2.3", want: semver{major: 1, minor: 2, patch: 3}},
+		{version: "v1.2.3", want: semver{major: 1, minor: 2, patch: 3}},
+		{version: " v1.2 ", want: semver{major: 1, minor: 2}},
+		{version: "2", want: semver{major: 2}},
+		{version: "1.0.0-rc.1", want: semver{major: 1, prerelease: "rc.1"}},
+		{version: "1.0.0+build.5", want: semver{major: 1}},
+		{version: "1.0.0-beta+build", want: semver{major: 1, prerelease: "beta"}},
+		{version: "1.2.3.4", wantErr: true},
+		{version: "nightly", wantErr: true},
+		{version: "1.x", wantErr: true},
+		{version: "1.-2", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSemver(tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSemver(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("parseSemver(%q) = %+v, want %+v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestCompareSemver(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.0.0", b: "1.0.0", want: 0},
+		{a: "v1.0.0", b: "1.0.0", want: 0},
+		{a: "1.10.0", b: "1.9.0", want: 1},
+		{a: "1.9.9", b: "2.0.0", want: -1},
+		{a: "1.0.1", b: "1.0.0", want: 1},
+		{a: "1.0.0", b: "1.0.0-rc.1", want: 1},
+		{a: "1.0.0-rc.1", b: "1.0.0", want: -1},
+		{a: "1.0.0-rc.10", b: "1.0.0-rc.2", want: 1},
+		{a: "1.0.0-alpha", b: "1.0.0-beta", want: -1},
+		{a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{a: "1.0.0-1", b: "1.0.0-alpha", want: -1},
+		{a: "1.0.0-beta.11", b: "1.0.0-beta.2", want: 1},
+		{a: "1.0.0-rc.1", b: "1.0.0-rc.1", want: 0},
+	}
+	for _, tt := range tests {
+		a, err := parseSemver(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := parseSemver(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := compareSemver(a, b); got != tt.want {
+			t.Errorf("compareSemver(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSatisfiesConstraint(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+		wantErr    bool
+	}{
+		{version: "1.2.3", constraint: "1.2.3", want: true},
+		{version: "1.2.3", constraint: "=1.2.3", want: true},
+		{version: "1.2.4", constraint: "1.2.3", want: false},
+		{version: "1.2.3", constraint: "!=1.2.3", want: false},
+		{version: "1.2.3", constraint: ">=1.2.0", want: true},
+		{version: "1.1.9", constraint: ">=1.2.0", want: false},
+		{version: "1.2.0", constraint: ">1.2.0", want: false},
+		{version: "1.2.0", constraint: "<1.3", want: true},
+		{version: "1.3.0", constraint: "<=1.3", want: true},
+		{version: "1.9.0", constraint: "^1.2", want: true},
+		{version: "2.0.0", constraint: "^1.2", want: false},
+		{version: "1.1.0", constraint: "^1.2", want: false},
+		{version: "1.2.9", constraint: "~1.2.1", want: true},
+		{version: "1.3.0", constraint: "~1.2.1", want: false},
+		{version: "1.5.0", constraint: ">=1.2, <2", want: true},
+		{version: "2.0.0", constraint: ">=1.2, <2", want: false},
+		{version: "2.0.0-rc.1", constraint: "<2", want: true},
+		{version: "v1.2.3", constraint: ">= v1.2.3", want: true},
+		{version: "1.2.3", constraint: "", wantErr: true},
+		{version: "1.2.3", constraint: ">=latest", wantErr: true},
+		{version: "unknown", constraint: ">=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := satisfiesConstraint(tt.version, tt.constraint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("satisfiesConstraint(%q, %q) error = %v, wantErr %v", tt.version, tt.constraint, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("satisfiesConstraint(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package fracture
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestProject(t *testing.T, deps DepsFile, lock LockFile, opts Options) *Manager {
+	t.Helper()
+	dir := t.TempDir()
+	data, err := json.Marshal(deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, DepsFileName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts.ConfigPath = DepsFileName
+	opts.CacheDir = filepath.Join(dir, "cache")
+	opts.TmpDir = dir
+	opts.Out = io.Discard
+	pm, err := NewManager(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm.workDir = dir
+	if lock != nil {
+		if err := pm.saveLockFile(lock); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return pm
+}
+func TestVendorUsesLockedVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content of " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	deps := DepsFile{
+		"locked": {Source: server.URL + "/locked", Type: "file", Path: "bin"},
+		"new":    {Source: server.URL + "/new", Type: "file", Path: "bin"},
+	}
+	lock := LockFile{
+		"locked": {Name: "locked", Source: server.URL + "/locked", Type: "file", Path: "bin", Version: "recorded", Hash: "recorded", URL: server.URL + "/locked", Asset: "locked"},
+	}
+	pm := newTestProject(t, deps, lock, Options{AllowInsecureHTTP: true})
+	if err := pm.Vendor(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := pm.loadVendorManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLock, err := pm.loadLockFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := manifest["locked"].Version; got != "recorded" {
+		t.Errorf("vendored version of a locked dependency = %q, want the locked one", got)
+	}
+	if got := newLock["locked"]; got.Version != "recorded" || got.SHA256 != manifest["locked"].SHA256 {
+		t.Errorf("lock entry of a locked dependency = %+v, want the locked version with the vendored checksum", got)
+	}
+	if got := newLock["new"].Version; got == "" || got != manifest["new"].SHA256 {
+		t.Errorf("lock version of a new dependency = %q, want its content hash %q", got, manifest["new"].SHA256)
+	}
+}
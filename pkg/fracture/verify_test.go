@@ -0,0 +1,92 @@
+package fracture
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(dir string) error
+		wantFail string
+	}{
+		{"unchanged", func(string) error { return nil }, ""},
+		{"modified file", func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "bin", "tool"), []byte("patched"), 0755)
+		}, "tool"},
+		{"missing file", func(dir string) error {
+			return os.Remove(filepath.Join(dir, "bin", "tool"))
+		}, "tool"},
+		{"file added to an extracted tree", func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "sdk", "extra"), []byte("extra"), 0644)
+		}, "sdk"},
+		{"git metadata of a repository", func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "repo", ".git", "index"), []byte("refreshed"), 0644)
+		}, ""},
+	}
+	for _, tt := range tests {
+		pm := newTestProject(t, DepsFile{}, nil, Options{})
+		for _, file := range []string{"bin/tool", "sdk/lib/a", "repo/README", "repo/.git/index"} {
+			path := filepath.Join(pm.workDir, filepath.FromSlash(file))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		lock := make(LockFile)
+		for name, resolved := range map[string]*ResolvedDependency{
+			"tool": {Name: "tool", Type: "binary", ExpandedPath: "bin", Files: []string{filepath.Join(pm.workDir, "bin", "tool")}},
+			"sdk":  {Name: "sdk", Type: "source", ExpandedPath: "sdk", Dependency: Dependency{Extract: true}},
+			"repo": {Name: "repo", Type: "repository", ExpandedPath: "repo"},
+		} {
+			lockDep := resolved.lockDependency()
+			lockDep.Installed = pm.installedChecksums(resolved)
+			lock[name] = lockDep
+		}
+		lock["old"] = LockDependency{Name: "old", Type: "binary", Path: "bin"}
+		if err := pm.saveLockFile(lock); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tt.change(pm.workDir); err != nil {
+			t.Fatal(err)
+		}
+		err := pm.Verify(context.Background())
+		switch {
+		case tt.wantFail == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.wantFail != "" && (err == nil || !strings.HasSuffix(err.Error(), "dependencies: "+tt.wantFail)):
+			t.Errorf("%s: err = %v, want only %s to fail", tt.name, err, tt.wantFail)
+		}
+		if _, err := os.Stat(pm.hashCachePath()); err != nil {
+			t.Errorf("%s: hash cache was not saved: %v", tt.name, err)
+		}
+	}
+}
+func TestVerifyInstalled(t *testing.T) {
+	pm := newTestProject(t, DepsFile{}, nil, Options{})
+	if err := os.MkdirAll(filepath.Join(pm.workDir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pm.workDir, "bin", "tool"), []byte("release one"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := pm.verifyInstalled(make(hashCache), LockDependency{Installed: map[string]string{"bin/tool": "other", "bin/gone": "other"}})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("err = %v, want a checksum mismatch", err)
+	}
+	for _, want := range []string{"bin/gone is missing", "bin/tool was modified"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want it to mention %q", err, want)
+		}
+	}
+}